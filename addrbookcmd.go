@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/notional-labs/tinyseed/seed"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// newAddrBookCmd implements `tinyseed addrbook`.  Every command works on the
+// book given by --file, or addr_book_file, along with its shards.
+func newAddrBookCmd(opts *cliOptions) *cobra.Command {
+	var file string
+
+	// loadConfig points the config at --file
+	loadConfig := func(flags *pflag.FlagSet) (seed.Config, error) {
+		cfg, err := opts.loadConfig(flags)
+		if err != nil {
+			return cfg, err
+		}
+		if flags.Changed("file") {
+			cfg.AddrBookFile = file
+		}
+		return cfg, nil
+	}
+
+	cmd := &cobra.Command{
+		Use:   "addrbook",
+		Short: "Inspect the address book",
+	}
+	cmd.PersistentFlags().StringVar(&file, "file", "", "address book to work on, along with its shards (defaults to addr_book_file)")
+	cmd.AddCommand(
+		newAddrBookDumpCmd(loadConfig),
+		newAddrBookExportCmd(loadConfig),
+		newAddrBookImportCmd(loadConfig),
+		newAddrBookVerifyHashCmd(loadConfig),
+	)
+	return cmd
+}
+
+// newAddrBookDumpCmd implements `tinyseed addrbook dump`
+func newAddrBookDumpCmd(loadConfig func(*pflag.FlagSet) (seed.Config, error)) *cobra.Command {
+	var shuffle bool
+
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Print every address, one per line",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("shuffle") {
+				shuffle = cfg.AddressShuffleOnExport
+			}
+
+			entries, err := seed.LoadAddrBookEntries(seed.AddrBookFiles(cfg))
+			if err != nil {
+				return err
+			}
+			addrs := make([]*p2p.NetAddress, 0, len(entries))
+			for _, entry := range entries {
+				addrs = append(addrs, entry.Addr)
+			}
+			if shuffle {
+				if addrs, err = seed.ShufflePeerList(addrs); err != nil {
+					return err
+				}
+			}
+			return seed.WritePeerList(cmd.OutOrStdout(), addrs)
+		},
+	}
+	cmd.Flags().BoolVar(&shuffle, "shuffle", false, "print entries in random order (defaults to address_shuffle_on_export)")
+	return cmd
+}
+
+// newAddrBookExportCmd implements `tinyseed addrbook export`
+func newAddrBookExportCmd(loadConfig func(*pflag.FlagSet) (seed.Config, error)) *cobra.Command {
+	var format string
+	var goodOnly, shuffle bool
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print the addresses in a form other nodes can use",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("shuffle") {
+				cfg.AddressShuffleOnExport = shuffle
+			}
+			entries, err := seed.ExportAddrBook(cfg, goodOnly, limit)
+			if err != nil {
+				return err
+			}
+			return seed.WritePeers(cmd.OutOrStdout(), format, entries)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&format, "format", seed.PeerFormatSeeds, "seeds, persistent_peers, csv or json")
+	flags.BoolVar(&goodOnly, "good-only", false, "only export addresses that have been marked good")
+	flags.IntVar(&limit, "limit", 0, "export at most this many addresses, the most recently successful ones (0 exports all)")
+	flags.BoolVar(&shuffle, "shuffle", false, "export in random order instead of most recently successful first (defaults to address_shuffle_on_export)")
+	return cmd
+}
+
+// newAddrBookImportCmd implements `tinyseed addrbook import`
+func newAddrBookImportCmd(loadConfig func(*pflag.FlagSet) (seed.Config, error)) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "import [input]",
+		Short: "Add addresses to the book",
+		Long:  "Reads stdin without input or when input is -.  Stop the seed first, or it will overwrite the book.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			in := cmd.InOrStdin()
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			addrs, err := seed.ReadPeers(in, format)
+			if err != nil {
+				return err
+			}
+			if len(addrs) == 0 {
+				return errors.New("no addresses to import")
+			}
+
+			summary, err := seed.ImportAddrBook(cfg, addrs)
+			for _, rejected := range summary.Rejected {
+				fmt.Fprintln(cmd.ErrOrStderr(), rejected)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %d added, %d already known, %d rejected\n",
+				cfg.AddrBookFile, summary.Added, summary.Skipped, len(summary.Rejected))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", seed.PeerFormatSeeds, "seeds, persistent_peers, csv or json")
+	return cmd
+}
+
+// newAddrBookVerifyHashCmd implements `tinyseed addrbook verify-hash`
+func newAddrBookVerifyHashCmd(loadConfig func(*pflag.FlagSet) (seed.Config, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-hash",
+		Short: "Check the book against its sha256 sidecar, failing if it has none",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			for _, path := range seed.AddrBookFiles(cfg) {
+				if err := seed.RequireAddrBookHash(path); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: OK\n", path)
+			}
+			return nil
+		},
+	}
+}
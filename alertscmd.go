@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/notional-labs/tinyseed/seed"
+	"github.com/spf13/cobra"
+)
+
+// newGenerateAlertsCmd implements `tinyseed generate-alerts`, writing
+// prometheus alerting rules for the seed's metrics
+func newGenerateAlertsCmd() *cobra.Command {
+	var thresholds seed.AlertThresholds
+	var output, job string
+
+	cmd := &cobra.Command{
+		Use:   "generate-alerts",
+		Short: "Write prometheus alerting rules for the seed",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			thresholds.MetricsJobSelector = fmt.Sprintf("job=%q", job)
+			if output == "-" {
+				return seed.WriteAlertRules(cmd.OutOrStdout(), thresholds)
+			}
+
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if err := seed.WriteAlertRules(f, thresholds); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.IntVar(&thresholds.WarnPeersBelow, "warn-peers-below", 10, "warn when the seed has fewer peers than this")
+	flags.IntVar(&thresholds.CritPeersBelow, "crit-peers-below", 3, "page when the seed has fewer peers than this")
+	flags.IntVar(&thresholds.MaxPeerChurn, "max-peer-churn", 500, "warn when the peer count changes more often than this within the churn window")
+	flags.DurationVar(&thresholds.ChurnWindow, "churn-window", 10*time.Minute, "window over which peer churn is measured")
+	flags.DurationVar(&thresholds.UnreachableFor, "unreachable-for", 5*time.Minute, "how long the seed must be unscrapeable before alerting")
+	flags.DurationVar(&thresholds.StagnationWindow, "stagnation-window", 6*time.Hour, "warn when the address book size has not changed for this long")
+	flags.StringVar(&job, "job", "tinyseed", "prometheus job name the seed is scraped under")
+	flags.StringVar(&output, "output", "alerts.yaml", "file to write the rules to (- for stdout)")
+	return cmd
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/notional-labs/tinyseed/seed"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/version"
+)
+
+// configFile is where the config lives, relative to the home directory
+const configFile = "config/config.toml"
+
+// cliOptions are the flags shared by every command
+type cliOptions struct {
+	home   string
+	config string
+
+	chainID         string
+	seeds           string
+	listenAddress   string
+	addrBookStrict  bool
+	maxInbound      int
+	maxOutbound     int
+	nodeKeyFile     string
+	addrBookFile    string
+	externalAddress string
+}
+
+// configFilePath returns where the config lives: the --config file, or
+// config/config.toml in the home directory
+func (o *cliOptions) configFilePath() string {
+	if o.config != "" {
+		return o.config
+	}
+	return filepath.Join(o.home, configFile)
+}
+
+// defaults returns the built-in config for the home directory
+func (o *cliOptions) defaults() seed.Config {
+	return *seed.DefaultConfig(o.home)
+}
+
+// loadConfig reads the config file and applies the ID, SEEDS and
+// LISTENADDRESS environment variables, the flags and then the TINYSEED_*
+// environment variables on top of it
+func (o *cliOptions) loadConfig(flags *pflag.FlagSet) (seed.Config, error) {
+	// defaults are only written to the config file in the home directory
+	if o.config != "" {
+		if _, err := os.Stat(o.config); err != nil {
+			return seed.Config{}, fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(o.home, filepath.Dir(configFile)), os.ModePerm); err != nil {
+		return seed.Config{}, err
+	}
+	cfg, err := seed.LoadConfig(o.configFilePath(), o.home, o.defaults())
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if id := os.Getenv("ID"); id != "" {
+		cfg.ChainID = id
+	}
+	if seeds := os.Getenv("SEEDS"); seeds != "" {
+		cfg.Seeds = seeds
+	}
+	if laddr := os.Getenv("LISTENADDRESS"); laddr != "" {
+		cfg.ListenAddress = laddr
+	}
+
+	if flags.Changed("chain-id") {
+		cfg.ChainID = o.chainID
+	}
+	if flags.Changed("seeds") {
+		cfg.Seeds = o.seeds
+	}
+	if flags.Changed("laddr") {
+		cfg.ListenAddress = o.listenAddress
+	}
+	if flags.Changed("addr-book-strict") {
+		cfg.AddrBookStrict = o.addrBookStrict
+	}
+	if flags.Changed("max-num-inbound-peers") {
+		cfg.MaxNumInboundPeers = o.maxInbound
+	}
+	if flags.Changed("max-num-outbound-peers") {
+		cfg.MaxNumOutboundPeers = o.maxOutbound
+	}
+	if flags.Changed("node-key-file") {
+		cfg.NodeKeyFile = o.nodeKeyFile
+	}
+	if flags.Changed("addr-book-file") {
+		cfg.AddrBookFile = o.addrBookFile
+	}
+	if flags.Changed("external-address") {
+		cfg.ExternalAddress = o.externalAddress
+	}
+
+	cfg, err = seed.ApplyEnv(cfg)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ResolvePaths(o.home)
+	return cfg, nil
+}
+
+// run starts a seed for cfg and stops it on SIGINT or SIGTERM
+func run(cfg seed.Config) error {
+	s, err := seed.New(cfg)
+	if err != nil {
+		return err
+	}
+	if err := s.Start(); err != nil {
+		return err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		s.Stop()
+	}()
+
+	s.Wait()
+	return nil
+}
+
+// NewRootCmd builds the tinyseed command line.  Running it without a
+// command starts the seed.
+func NewRootCmd() *cobra.Command {
+	opts := &cliOptions{}
+
+	start := func(cmd *cobra.Command, _ []string) error {
+		cfg, err := opts.loadConfig(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		return run(cfg)
+	}
+
+	root := &cobra.Command{
+		Use:           "tinyseed",
+		Short:         "A seed node for Tendermint networks",
+		Args:          cobra.NoArgs,
+		RunE:          start,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	home := filepath.Join("~", ".tinyseed")
+	if userHomeDir, err := homedir.Dir(); err == nil {
+		home = filepath.Join(userHomeDir, ".tinyseed")
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.home, "home", home, "directory holding the config, node key and address book")
+	flags.StringVar(&opts.config, "config", "", fmt.Sprintf("config file to use instead of %s in the home directory, plain or encrypted", configFile))
+	flags.StringVar(&opts.chainID, "chain-id", "", "network identifier (overrides chain_id)")
+	flags.StringVar(&opts.seeds, "seeds", "", "comma separated id@host:port seed nodes (overrides seeds)")
+	flags.StringVar(&opts.listenAddress, "laddr", "", "address to listen for incoming connections (overrides laddr)")
+	flags.BoolVar(&opts.addrBookStrict, "addr-book-strict", true, "strict routability rules (overrides addr_book_strict)")
+	flags.IntVar(&opts.maxInbound, "max-num-inbound-peers", 0, "maximum number of inbound connections (overrides max_num_inbound_peers)")
+	flags.IntVar(&opts.maxOutbound, "max-num-outbound-peers", 0, "maximum number of outbound connections (overrides max_num_outbound_peers)")
+	flags.StringVar(&opts.nodeKeyFile, "node-key-file", "", "path to node_key (overrides node_key_file)")
+	flags.StringVar(&opts.addrBookFile, "addr-book-file", "", "path to the address book (overrides addr_book_file)")
+	flags.StringVar(&opts.externalAddress, "external-address", "", "publicly reachable address of this seed (overrides external_address)")
+
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "start",
+			Short: "Run the seed",
+			Args:  cobra.NoArgs,
+			RunE:  start,
+		},
+		newInitCmd(opts),
+		&cobra.Command{
+			Use:   "show-node-id",
+			Short: "Print the node ID the seed identifies itself with",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				cfg, err := opts.loadConfig(cmd.Flags())
+				if err != nil {
+					return err
+				}
+				nodeKey, err := seed.LoadSeedNodeKey(cfg, log.NewNopLogger())
+				if err != nil {
+					return err
+				}
+				fmt.Println(nodeKey.ID())
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "version",
+			Short: "Print the tinyseed and tendermint versions",
+			Args:  cobra.NoArgs,
+			Run: func(*cobra.Command, []string) {
+				fmt.Printf("tinyseed %s\n", seed.Version)
+				fmt.Printf("tendermint %s (p2p protocol %d)\n", version.TMCoreSemVer, version.P2PProtocol)
+			},
+		},
+		newGenerateAlertsCmd(),
+		newDiagnosePeerCmd(opts),
+		newAddrBookCmd(opts),
+		newStatsCmd(opts),
+		newReplayAddressesCmd(opts),
+		newConfigCmd(opts),
+	)
+	return root
+}
+
+// newInitCmd implements `tinyseed init`, writing the default config
+func newInitCmd(opts *cliOptions) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write the default config",
+		Args:  cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			path := opts.configFilePath()
+			if _, err := os.Stat(path); err == nil && !force {
+				return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+			}
+			if err := seed.WriteConfigFile(path, opts.defaults()); err != nil {
+				return err
+			}
+			fmt.Printf("wrote %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing config file")
+	return cmd
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/notional-labs/tinyseed/seed"
+	"github.com/spf13/cobra"
+	"github.com/tendermint/tendermint/libs/tempfile"
+)
+
+// newConfigCmd implements `tinyseed config`
+func newConfigCmd(opts *cliOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Encrypt, decrypt or watch the config file",
+	}
+	cmd.AddCommand(
+		newConfigCryptCmd(opts, "encrypt", "Encrypt the config file", "", ".enc", seed.EncryptConfig),
+		newConfigCryptCmd(opts, "decrypt", "Decrypt an encrypted config file", ".enc", "", seed.DecryptConfig),
+		newConfigWatchCmd(opts),
+	)
+	return cmd
+}
+
+// newConfigCryptCmd implements `tinyseed config encrypt` and `config
+// decrypt`.  By default they read and write the config file with the given
+// suffixes.
+func newConfigCryptCmd(opts *cliOptions, use, short, inSuffix, outSuffix string, transform func([]byte, string) ([]byte, error)) *cobra.Command {
+	var in, out, passphraseEnv string
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !cmd.Flags().Changed("in") {
+				in = opts.configFilePath() + inSuffix
+			}
+			if !cmd.Flags().Changed("out") {
+				out = opts.configFilePath() + outSuffix
+			}
+
+			passphrase := os.Getenv(passphraseEnv)
+			if strings.TrimSpace(passphrase) == "" {
+				return fmt.Errorf("passphrase variable %s is not set", passphraseEnv)
+			}
+
+			data, err := os.ReadFile(in)
+			if err != nil {
+				return err
+			}
+			result, err := transform(data, passphrase)
+			if err != nil {
+				return err
+			}
+			return tempfile.WriteFileAtomic(out, result, 0600)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&passphraseEnv, "passphrase-env", seed.ConfigPassphraseEnv, "environment variable holding the passphrase")
+	flags.StringVar(&in, "in", "", fmt.Sprintf("file to read (defaults to %s%s)", configFile, inSuffix))
+	flags.StringVar(&out, "out", "", fmt.Sprintf("file to write (defaults to %s%s)", configFile, outSuffix))
+	return cmd
+}
+
+// newConfigWatchCmd implements `tinyseed config watch`, printing every
+// setting that changes when the config file is saved
+func newConfigWatchCmd(opts *cliOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print the settings that change whenever the config file is saved",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path := opts.configFilePath()
+			defaults := opts.defaults()
+			load := func() (seed.Config, error) {
+				data, err := seed.ReadConfigFile(path)
+				if err != nil {
+					return defaults, err
+				}
+				return seed.ParseConfig(data, defaults)
+			}
+
+			current, err := load()
+			if err != nil {
+				return err
+			}
+
+			// editors often replace the file rather than write to it, so
+			// watch the directory and pick out events for the config file
+			watcher, err := fsnotify.NewWatcher()
+			if err != nil {
+				return err
+			}
+			defer watcher.Close()
+			if err := watcher.Add(filepath.Dir(path)); err != nil {
+				return err
+			}
+
+			interrupt := make(chan os.Signal, 1)
+			signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(interrupt)
+
+			w := cmd.OutOrStdout()
+			fmt.Fprintf(w, "watching %s, press Ctrl-C to stop\n", path)
+			for {
+				select {
+				case event, ok := <-watcher.Events:
+					if !ok {
+						return nil
+					}
+					if filepath.Clean(event.Name) != filepath.Clean(path) || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+						continue
+					}
+
+					next, err := load()
+					if err != nil {
+						fmt.Fprintf(w, "%s error: %v\n", time.Now().Format(time.RFC3339), err)
+						continue
+					}
+					for _, change := range current.Diff(next) {
+						fmt.Fprintf(w, "%s %s\n", time.Now().Format(time.RFC3339), change)
+					}
+					current = next
+
+				case err, ok := <-watcher.Errors:
+					if !ok {
+						return nil
+					}
+					return err
+
+				case <-interrupt:
+					return nil
+				}
+			}
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/notional-labs/tinyseed/seed"
+	"github.com/spf13/cobra"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// newDiagnosePeerCmd implements `tinyseed diagnose-peer`.  The peer is
+// expected on the network given by --chain-id or the config.
+func newDiagnosePeerCmd(opts *cliOptions) *cobra.Command {
+	var timeout time.Duration
+	var p2pVersion, blockVersion uint64
+
+	cmd := &cobra.Command{
+		Use:   "diagnose-peer <id@host:port>",
+		Short: "Check whether a peer is reachable and on the expected network",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := opts.loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			addr, err := p2p.NewNetAddressString(args[0])
+			if err != nil {
+				return err
+			}
+
+			protocol := cfg.ProtocolVersion()
+			if cmd.Flags().Changed("p2p-version") {
+				protocol.P2P = p2pVersion
+			}
+			if cmd.Flags().Changed("block-version") {
+				protocol.Block = blockVersion
+			}
+
+			d := seed.DiagnosePeer(addr, cfg.ChainID, protocol, timeout)
+			d.Print(cmd.OutOrStdout())
+			if !d.OK() {
+				return errors.New("peer diagnosis failed")
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.DurationVar(&timeout, "timeout", 10*time.Second, "timeout for each step")
+	flags.Uint64Var(&p2pVersion, "p2p-version", 0, "p2p protocol version to advertise (defaults to the seed's)")
+	flags.Uint64Var(&blockVersion, "block-version", 0, "block protocol version to advertise (defaults to the seed's)")
+	return cmd
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/notional-labs/tinyseed/seed"
+	"github.com/spf13/cobra"
+)
+
+// newReplayAddressesCmd implements `tinyseed replay-addresses`, adding the
+// addresses in a JSON file to a running seed through its admin API
+func newReplayAddressesCmd(opts *cliOptions) *cobra.Command {
+	var file, admin string
+	var parallel int
+
+	cmd := &cobra.Command{
+		Use:   "replay-addresses",
+		Short: "Add addresses to a running seed through its admin API",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := opts.loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("admin") {
+				admin = cfg.AdminListenAddress
+			}
+			if admin == "" {
+				return errors.New("missing --admin and admin_listen_address is not set")
+			}
+			if parallel < 1 {
+				return fmt.Errorf("--parallel must be at least 1, got %d", parallel)
+			}
+
+			bz, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			var addrs []string
+			if err := json.Unmarshal(bz, &addrs); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+
+			url := strings.TrimRight(admin, "/") + "/api/addrbook/add"
+			if !strings.Contains(admin, "://") {
+				url = "http://" + url
+			}
+
+			summary := seed.ReplayAddresses(url, addrs, parallel)
+			for _, err := range summary.Errors {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "added: %d, skipped: %d, rejected: %d\n", summary.Added, summary.Skipped, summary.Rejected)
+			if summary.Failed > 0 {
+				return fmt.Errorf("%d addresses could not be sent", summary.Failed)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&file, "file", "", "JSON array of id@host:port addresses")
+	flags.StringVar(&admin, "admin", "", "admin API address of the running seed (defaults to admin_listen_address)")
+	flags.IntVar(&parallel, "parallel", 1, "number of addresses added concurrently")
+	cmd.MarkFlagRequired("file") //nolint:errcheck
+	return cmd
+}
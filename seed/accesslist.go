@@ -0,0 +1,207 @@
+package seed
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pelletier/go-toml"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// AccessList decides which peers the seed talks to and which addresses it
+// stores and gossips.  Entries are node IDs, CIDR ranges or single IPs.
+// Denied entries always lose; when allow is not empty, nothing else gets in.
+type AccessList struct {
+	allowIDs  map[p2p.ID]struct{}
+	denyIDs   map[p2p.ID]struct{}
+	allowNets []*net.IPNet
+	denyNets  []*net.IPNet
+}
+
+// accessListFile is the format of Config.AccessListFile
+type accessListFile struct {
+	Allow []string `toml:"allow"`
+	Deny  []string `toml:"deny"`
+}
+
+// ParseAccessList decodes an access list file, e.g.
+//
+//	allow = ["10.0.0.0/8"]
+//	deny  = ["3f2c8a9d5e7b1c4f6a0d2e8b9c7f5a3d1e6b4c2a", "192.0.2.7"]
+func ParseAccessList(data []byte) (*AccessList, error) {
+	var file accessListFile
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	l := &AccessList{
+		allowIDs: make(map[p2p.ID]struct{}),
+		denyIDs:  make(map[p2p.ID]struct{}),
+	}
+	var err error
+	if l.allowNets, err = parseAccessEntries(file.Allow, l.allowIDs); err != nil {
+		return nil, fmt.Errorf("allow: %w", err)
+	}
+	if l.denyNets, err = parseAccessEntries(file.Deny, l.denyIDs); err != nil {
+		return nil, fmt.Errorf("deny: %w", err)
+	}
+	return l, nil
+}
+
+// parseAccessEntries adds the node IDs among entries to ids and returns the
+// rest as networks
+func parseAccessEntries(entries []string, ids map[p2p.ID]struct{}) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, err
+			}
+			nets = append(nets, ipNet)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			if bz, err := hex.DecodeString(entry); err != nil || len(bz) != p2p.IDByteLength {
+				return nil, fmt.Errorf("%q is neither a node ID, a CIDR range nor an IP", entry)
+			}
+			ids[p2p.ID(strings.ToLower(entry))] = struct{}{}
+		}
+	}
+	return nets, nil
+}
+
+// Permits reports whether the peer with id at ip may connect and be gossiped
+func (l *AccessList) Permits(id p2p.ID, ip net.IP) bool {
+	if _, ok := l.denyIDs[id]; ok || containsIP(l.denyNets, ip) {
+		return false
+	}
+	if len(l.allowIDs) == 0 && len(l.allowNets) == 0 {
+		return true
+	}
+	_, ok := l.allowIDs[id]
+	return ok || containsIP(l.allowNets, ip)
+}
+
+// PermitsIP is Permits for connections whose node ID is not known yet.  An
+// IP that only an allowed node ID could redeem is let through.
+func (l *AccessList) PermitsIP(ip net.IP) bool {
+	if containsIP(l.denyNets, ip) {
+		return false
+	}
+	return len(l.allowNets) == 0 || len(l.allowIDs) > 0 || containsIP(l.allowNets, ip)
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// accessListFilter applies the access list at path and reloads it on SIGHUP
+type accessListFilter struct {
+	path   string
+	logger log.Logger
+
+	mtx  sync.RWMutex
+	list *AccessList
+}
+
+// newAccessListFilter loads the access list at path
+func newAccessListFilter(path string, logger log.Logger) (*accessListFilter, error) {
+	f := &accessListFilter{path: path, logger: logger}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload reads the access list again.  The old list stays in force if the
+// file cannot be read.
+func (f *accessListFilter) Reload() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	list, err := ParseAccessList(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.path, err)
+	}
+
+	f.mtx.Lock()
+	f.list = list
+	f.mtx.Unlock()
+	return nil
+}
+
+// Run reloads the access list on SIGHUP until quit is closed, calling
+// onReload after each successful reload
+func (f *accessListFilter) Run(quit <-chan struct{}, onReload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			if err := f.Reload(); err != nil {
+				f.logger.Error("failed to reload access list, keeping the old one", "err", err)
+				continue
+			}
+			f.logger.Info("reloaded access list", "file", f.path)
+			onReload()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// Permits reports whether addr may be stored and gossiped
+func (f *accessListFilter) Permits(addr *p2p.NetAddress) bool {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.list.Permits(addr.ID, addr.IP)
+}
+
+// FilterConn is a p2p.ConnFilterFunc refusing denied IPs before the
+// handshake
+func (f *accessListFilter) FilterConn(_ p2p.ConnSet, c net.Conn, _ []net.IP) error {
+	remote, ok := c.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	if !f.list.PermitsIP(remote.IP) {
+		return fmt.Errorf("%s is not permitted by the access list", remote.IP)
+	}
+	return nil
+}
+
+// FilterPeer is a p2p.PeerFilterFunc refusing denied node IDs and IPs
+func (f *accessListFilter) FilterPeer(_ p2p.IPeerSet, peer p2p.Peer) error {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	if !f.list.Permits(peer.ID(), peer.RemoteIP()) {
+		return fmt.Errorf("peer %s at %s is not permitted by the access list", peer.ID(), peer.RemoteIP())
+	}
+	return nil
+}
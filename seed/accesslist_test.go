@@ -0,0 +1,29 @@
+package seed
+
+import (
+	"net"
+	"testing"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+func TestParseAccessListDocExample(t *testing.T) {
+	l, err := ParseAccessList([]byte(`
+allow = ["10.0.0.0/8"]
+deny  = ["3f2c8a9d5e7b1c4f6a0d2e8b9c7f5a3d1e6b4c2a", "192.0.2.7"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const id = p2p.ID("3f2c8a9d5e7b1c4f6a0d2e8b9c7f5a3d1e6b4c2a")
+	if l.Permits(id, net.ParseIP("10.0.0.1")) {
+		t.Error("a denied node ID was permitted")
+	}
+	if l.Permits("0123456789abcdef0123456789abcdef01234567", net.ParseIP("192.0.2.7")) {
+		t.Error("a denied IP was permitted")
+	}
+	if !l.Permits("0123456789abcdef0123456789abcdef01234567", net.ParseIP("10.0.0.1")) {
+		t.Error("an allowed range was refused")
+	}
+}
@@ -0,0 +1,175 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+const (
+	// adaptiveCheckInterval is how often memory pressure is sampled
+	adaptiveCheckInterval = 10 * time.Second
+
+	// heap usage, as a fraction of the configured limit, above which the
+	// inbound limit shrinks and below which it is restored
+	adaptiveShrinkAbove  = 0.8
+	adaptiveRestoreBelow = 0.6
+
+	// adaptiveShrinkStep is the fraction of the current limit shed per check
+	adaptiveShrinkStep = 0.1
+
+	cpuSecondsMetric = "/cpu/classes/total:cpu-seconds"
+)
+
+// adaptivePeerLimit lowers the inbound peer limit while the heap is close to
+// AdaptiveMaxPeersHeapLimit and restores it once pressure subsides
+type adaptivePeerLimit struct {
+	max       int64
+	limit     int64 // accessed atomically
+	heapLimit uint64
+	logger    log.Logger
+}
+
+// newAdaptivePeerLimit starts out allowing max inbound peers
+func newAdaptivePeerLimit(max int, heapLimit uint64, logger log.Logger) (*adaptivePeerLimit, error) {
+	if heapLimit == 0 {
+		return nil, errors.New("adaptive_max_peers requires adaptive_max_peers_heap_limit to be set")
+	}
+	return &adaptivePeerLimit{
+		max:       int64(max),
+		limit:     int64(max),
+		heapLimit: heapLimit,
+		logger:    logger,
+	}, nil
+}
+
+// Limit returns the current inbound peer limit
+func (a *adaptivePeerLimit) Limit() int {
+	return int(atomic.LoadInt64(&a.limit))
+}
+
+// FilterPeer is a p2p.PeerFilterFunc rejecting inbound peers above the
+// current limit
+func (a *adaptivePeerLimit) FilterPeer(peers p2p.IPeerSet, peer p2p.Peer) error {
+	if peer.IsOutbound() {
+		return nil
+	}
+
+	inbound := 0
+	for _, p := range peers.List() {
+		if !p.IsOutbound() {
+			inbound++
+		}
+	}
+	if inbound >= a.Limit() {
+		return fmt.Errorf("inbound peer limit lowered to %d under memory pressure", a.Limit())
+	}
+	return nil
+}
+
+// Run samples memory usage until quit is closed, adjusting the limit and
+// shedding inbound peers from sw as needed
+func (a *adaptivePeerLimit) Run(sw *p2p.Switch, quit <-chan struct{}) {
+	ticker := time.NewTicker(adaptiveCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.check(sw)
+		case <-quit:
+			return
+		}
+	}
+}
+
+// check compares heap usage with the configured limit once
+func (a *adaptivePeerLimit) check(sw *p2p.Switch) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	usage := float64(mem.HeapInuse) / float64(a.heapLimit)
+
+	limit := a.Limit()
+	switch {
+	case usage > adaptiveShrinkAbove:
+		newLimit := limit - int(float64(limit)*adaptiveShrinkStep)
+		if newLimit == limit {
+			newLimit--
+		}
+		if newLimit < 1 {
+			newLimit = 1
+		}
+		atomic.StoreInt64(&a.limit, int64(newLimit))
+		a.logger.Info("memory pressure, lowering inbound peer limit",
+			"heap-inuse", mem.HeapInuse, "heap-limit", a.heapLimit, "cpu-seconds", cpuSeconds(), "limit", newLimit)
+		a.shed(sw, newLimit)
+
+	case usage < adaptiveRestoreBelow && int64(limit) < a.max:
+		atomic.StoreInt64(&a.limit, a.max)
+		a.logger.Info("memory pressure relieved, restoring inbound peer limit",
+			"heap-inuse", mem.HeapInuse, "heap-limit", a.heapLimit, "cpu-seconds", cpuSeconds(), "limit", a.max)
+	}
+}
+
+// shed disconnects the longest-connected idle inbound peers until at most
+// limit remain
+func (a *adaptivePeerLimit) shed(sw *p2p.Switch, limit int) {
+	type inboundPeer struct {
+		peer      p2p.Peer
+		connected time.Duration
+		idle      bool
+	}
+
+	var inbound []inboundPeer
+	for _, peer := range sw.Peers().List() {
+		if peer.IsOutbound() {
+			continue
+		}
+		status := peer.Status()
+		inbound = append(inbound, inboundPeer{
+			peer:      peer,
+			connected: status.Duration,
+			idle:      minDuration(status.SendMonitor.Idle, status.RecvMonitor.Idle) >= adaptiveCheckInterval,
+		})
+	}
+	if len(inbound) <= limit {
+		return
+	}
+
+	// idle peers first, then the ones that have been around longest
+	sort.Slice(inbound, func(i, j int) bool {
+		if inbound[i].idle != inbound[j].idle {
+			return inbound[i].idle
+		}
+		return inbound[i].connected > inbound[j].connected
+	})
+
+	for _, ip := range inbound[:len(inbound)-limit] {
+		sw.StopPeerGracefully(ip.peer)
+	}
+}
+
+// cpuSeconds returns the CPU time used by the process so far, or -1 if the
+// runtime does not report it
+func cpuSeconds() float64 {
+	sample := []metrics.Sample{{Name: cpuSecondsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindFloat64 {
+		return -1
+	}
+	return sample[0].Value.Float64()
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
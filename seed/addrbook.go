@@ -0,0 +1,292 @@
+package seed
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/tempfile"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// AddrBookVersion is the current version of the serialized address book
+const AddrBookVersion uint32 = 1
+
+// bucketTypeNew matches the bucket type tendermint uses for new addresses
+const bucketTypeNew = 0x01
+
+// AddrBook mirrors the on-disk format of the tendermint address book, plus a
+// version so the format can be migrated
+type AddrBook struct {
+	Version uint32           `json:"version"`
+	Key     string           `json:"key"`
+	Addrs   []*AddrBookEntry `json:"addrs"`
+}
+
+// AddrBookEntry mirrors a single entry of the tendermint address book
+type AddrBookEntry struct {
+	Addr        *p2p.NetAddress `json:"addr"`
+	Src         *p2p.NetAddress `json:"src"`
+	Buckets     []int           `json:"buckets"`
+	Attempts    int32           `json:"attempts"`
+	BucketType  byte            `json:"bucket_type"`
+	LastAttempt time.Time       `json:"last_attempt"`
+	LastSuccess time.Time       `json:"last_success"`
+	LastBanTime time.Time       `json:"last_ban_time"`
+}
+
+// KnownAddress is the seed's view of an address book entry
+type KnownAddress struct {
+	Addr        *p2p.NetAddress
+	LastSeen    time.Time
+	LastSuccess time.Time
+	Attempts    int
+	Successes   int
+
+	// set by the crawler
+	LastProbe     time.Time
+	Latency       time.Duration
+	ProbeFailures int
+
+	// set when a GeoIP database is configured, empty if it has no answer
+	Country   string
+	Continent string
+}
+
+// SuccessRate returns the smoothed fraction of dial attempts that succeeded
+func (ka *KnownAddress) SuccessRate() float64 {
+	return float64(ka.Successes+1) / float64(ka.Attempts+2)
+}
+
+// addrBookMigrations upgrade an address book from version i to i+1
+var addrBookMigrations = []func(book *AddrBook){
+	migrateAddrBookV0,
+}
+
+// migrateAddrBookV0 fills in the fields that unversioned books may lack
+func migrateAddrBookV0(book *AddrBook) {
+	for _, a := range book.Addrs {
+		if a.Src == nil {
+			a.Src = a.Addr
+		}
+		if a.BucketType == 0 {
+			a.BucketType = bucketTypeNew
+		}
+	}
+}
+
+// LoadAddrBook reads the address book persisted at path and returns it along
+// with the version it was stored with.  Books written before versioning was
+// introduced are reported as version 0.  A missing file is not an error and
+// yields an empty book at the current version.
+func LoadAddrBook(path string) (*AddrBook, uint32, error) {
+	bz, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &AddrBook{Version: AddrBookVersion}, AddrBookVersion, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	book := &AddrBook{}
+	if err := json.Unmarshal(bz, book); err != nil {
+		return nil, 0, err
+	}
+	return book, book.Version, nil
+}
+
+// SaveAddrBook upgrades book to the current version and writes it to path,
+// along with its sha256 sidecar
+func SaveAddrBook(path string, book *AddrBook) error {
+	if book.Version > AddrBookVersion {
+		return fmt.Errorf("address book version %d is newer than supported version %d", book.Version, AddrBookVersion)
+	}
+	for ; book.Version < AddrBookVersion; book.Version++ {
+		addrBookMigrations[book.Version](book)
+	}
+
+	bz, err := json.MarshalIndent(book, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := tempfile.WriteFileAtomic(path, bz, 0644); err != nil {
+		return err
+	}
+	return WriteAddrBookHash(path)
+}
+
+// UpgradeAddrBook migrates the address book at path to the current version
+func UpgradeAddrBook(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	book, version, err := LoadAddrBook(path)
+	if err != nil {
+		return err
+	}
+	if version == AddrBookVersion {
+		return nil
+	}
+	return SaveAddrBook(path, book)
+}
+
+// addrBookWorkingFile is where tendermint's book for the book at path saves
+// itself.  tendermint saves on its own schedule and knows nothing of the
+// version or the sha256 sidecar, so path is only written by publishAddrBook.
+func addrBookWorkingFile(path string) string {
+	return path + ".live"
+}
+
+// checkoutAddrBook upgrades the book at path and copies it to its working
+// file for tendermint to load.  Without a book a stale working file is
+// removed, so both start out empty.
+func checkoutAddrBook(path string) error {
+	working := addrBookWorkingFile(path)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.Remove(working); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := UpgradeAddrBook(path); err != nil {
+		return err
+	}
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return tempfile.WriteFileAtomic(working, bz, 0644)
+}
+
+// publishAddrBook replaces the book at path with what tendermint last saved
+// to its working file, stamped with the current version
+func publishAddrBook(path string) error {
+	working := addrBookWorkingFile(path)
+	if _, err := os.Stat(working); os.IsNotExist(err) {
+		return nil
+	}
+	book, _, err := LoadAddrBook(working)
+	if err != nil {
+		return err
+	}
+	return SaveAddrBook(path, book)
+}
+
+// openAddrBook checks out every file the book of cfg is stored in and
+// returns the book, sharded when AddrBookShardCount is above 1, on their
+// working files.  The book is not started.
+func openAddrBook(cfg Config) (pex.AddrBook, error) {
+	for _, path := range AddrBookFiles(cfg) {
+		if err := checkoutAddrBook(path); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.AddrBookShardCount > 1 {
+		return NewSeedBalancer(cfg, cfg.AddrBookShardCount)
+	}
+	strict, err := cfg.RoutabilityStrict()
+	if err != nil {
+		return nil, err
+	}
+	return pex.NewAddrBook(addrBookWorkingFile(cfg.AddrBookFile), strict), nil
+}
+
+// LoadAddrBookEntries returns the entries of the books at paths, such as
+// the shards from AddrBookFiles
+func LoadAddrBookEntries(paths []string) ([]*AddrBookEntry, error) {
+	var entries []*AddrBookEntry
+	for _, path := range paths {
+		book, _, err := LoadAddrBook(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, entry := range book.Addrs {
+			if entry.Addr != nil {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// waitAddrBook waits for book to finish the save tendermint makes when it
+// stops
+func waitAddrBook(book pex.AddrBook) {
+	if w, ok := book.(interface{ Wait() }); ok {
+		w.Wait()
+	}
+}
+
+// WritePeerList writes addrs to w in id@host:port format, one per line
+func WritePeerList(w io.Writer, addrs []*p2p.NetAddress) error {
+	for _, addr := range addrs {
+		if _, err := fmt.Fprintln(w, addr.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadKnownAddresses builds KnownAddress entries from the address books at
+// paths
+func loadKnownAddresses(paths []string) (map[p2p.ID]*KnownAddress, error) {
+	entries, err := LoadAddrBookEntries(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[p2p.ID]*KnownAddress, len(entries))
+	for _, a := range entries {
+		ka := &KnownAddress{
+			Addr:        a.Addr,
+			LastSeen:    a.LastAttempt,
+			LastSuccess: a.LastSuccess,
+			Attempts:    int(a.Attempts),
+		}
+		if !a.LastSuccess.IsZero() {
+			// tendermint resets attempts on success, so count that one back in
+			ka.Attempts++
+			ka.Successes = 1
+			if a.LastSuccess.After(ka.LastSeen) {
+				ka.LastSeen = a.LastSuccess
+			}
+		}
+		known[a.Addr.ID] = ka
+	}
+	return known, nil
+}
+
+// ShufflePeerList returns a copy of addrs in random order.  The order comes
+// from crypto/rand so that repeated exports reveal nothing about how the
+// book is laid out in memory.
+func ShufflePeerList(addrs []*p2p.NetAddress) ([]*p2p.NetAddress, error) {
+	shuffled := make([]*p2p.NetAddress, len(addrs))
+	copy(shuffled, addrs)
+	if err := shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}); err != nil {
+		return nil, err
+	}
+	return shuffled, nil
+}
+
+// shuffle puts n elements in random order through swap, like rand.Shuffle
+// but drawing from crypto/rand
+func shuffle(n int, swap func(i, j int)) error {
+	// Fisher-Yates
+	for i := n - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+		swap(i, int(j.Int64()))
+	}
+	return nil
+}
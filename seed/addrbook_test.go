@@ -0,0 +1,114 @@
+package seed
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestShardedAddrBookRoundTrip(t *testing.T) {
+	cfg := DefaultConfig(t.TempDir())
+	cfg.AddrBookFile = filepath.Join(t.TempDir(), "addrbook.json")
+	cfg.AddrBookStrict = false
+	cfg.AddrBookShardCount = 3
+
+	book, err := openAddrBook(*cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.SetLogger(log.NewNopLogger())
+	if err := book.Start(); err != nil {
+		t.Fatal(err)
+	}
+	addrs := testAddrs(100)
+	for _, addr := range addrs[1:] {
+		if err := book.AddAddress(addr, addrs[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := book.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	waitAddrBook(book)
+
+	files := AddrBookFiles(*cfg)
+	for _, path := range files {
+		if err := publishAddrBook(path); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := LoadAddrBookEntries([]string{path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 0 {
+			t.Errorf("%s is empty", path)
+		}
+	}
+
+	known, err := loadKnownAddresses(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range addrs[1:] {
+		if _, ok := known[addr.ID]; !ok {
+			t.Errorf("%v was not loaded back", addr)
+		}
+	}
+}
+
+func TestExportAddrBookHonoursShuffle(t *testing.T) {
+	cfg := DefaultConfig(t.TempDir())
+	cfg.AddrBookFile = filepath.Join(t.TempDir(), "addrbook.json")
+
+	// entry i last succeeded i minutes ago
+	addrs := testAddrs(50)
+	book := &AddrBook{Version: AddrBookVersion}
+	now := time.Now()
+	for i, addr := range addrs {
+		book.Addrs = append(book.Addrs, &AddrBookEntry{
+			Addr:        addr,
+			Src:         addr,
+			BucketType:  bucketTypeOld,
+			LastSuccess: now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+	if err := SaveAddrBook(cfg.AddrBookFile, book); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.AddressShuffleOnExport = false
+	entries, err := ExportAddrBook(*cfg, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range entries {
+		if entry.Addr.ID != addrs[i].ID {
+			t.Fatalf("entry %d is %v, want the most recently successful first", i, entry.Addr)
+		}
+	}
+
+	cfg.AddressShuffleOnExport = true
+	sorted := true
+	for attempt := 0; attempt < 5 && sorted; attempt++ {
+		entries, err := ExportAddrBook(*cfg, false, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 20 {
+			t.Fatalf("exported %d entries, want 20", len(entries))
+		}
+		for i, entry := range entries {
+			if entry.LastSuccess.Before(now.Add(-19 * time.Minute)) {
+				t.Fatalf("%v is not among the 20 most recently successful", entry.Addr)
+			}
+			if entry.Addr.ID != addrs[i].ID {
+				sorted = false
+			}
+		}
+	}
+	if sorted {
+		t.Error("address_shuffle_on_export is set but the export stayed sorted")
+	}
+}
@@ -0,0 +1,260 @@
+package seed
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// bucketTypeOld matches the bucket type tendermint uses for addresses it
+// has marked good
+const bucketTypeOld = 0x02
+
+// Formats understood by `tinyseed addrbook export` and `import`
+const (
+	PeerFormatSeeds           = "seeds"
+	PeerFormatPersistentPeers = "persistent_peers"
+	PeerFormatCSV             = "csv"
+	PeerFormatJSON            = "json"
+)
+
+// peerCSVHeader is the header row of the csv format
+var peerCSVHeader = []string{"id", "host", "port", "bucket", "attempts", "last_attempt", "last_success"}
+
+// ExportedPeer is an address book entry in the json format
+type ExportedPeer struct {
+	ID          p2p.ID    `json:"id"`
+	Address     string    `json:"address"`
+	Bucket      string    `json:"bucket"`
+	Attempts    int32     `json:"attempts"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+}
+
+func exportedPeer(entry *AddrBookEntry) ExportedPeer {
+	bucket := bucketNew
+	if entry.BucketType == bucketTypeOld {
+		bucket = bucketOld
+	}
+	return ExportedPeer{
+		ID:          entry.Addr.ID,
+		Address:     entry.Addr.String(),
+		Bucket:      bucket,
+		Attempts:    entry.Attempts,
+		LastAttempt: entry.LastAttempt,
+		LastSuccess: entry.LastSuccess,
+	}
+}
+
+// WritePeers writes entries to w in format
+func WritePeers(w io.Writer, format string, entries []*AddrBookEntry) error {
+	switch format {
+	case PeerFormatSeeds, PeerFormatPersistentPeers:
+		addrs := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			addrs = append(addrs, entry.Addr.String())
+		}
+		_, err := fmt.Fprintf(w, "%s = %q\n", format, strings.Join(addrs, ","))
+		return err
+
+	case PeerFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(peerCSVHeader); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			peer := exportedPeer(entry)
+			if err := cw.Write([]string{
+				string(peer.ID),
+				entry.Addr.IP.String(),
+				strconv.Itoa(int(entry.Addr.Port)),
+				peer.Bucket,
+				strconv.Itoa(int(peer.Attempts)),
+				formatPeerTime(peer.LastAttempt),
+				formatPeerTime(peer.LastSuccess),
+			}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case PeerFormatJSON:
+		peers := make([]ExportedPeer, 0, len(entries))
+		for _, entry := range entries {
+			peers = append(peers, exportedPeer(entry))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(peers)
+
+	default:
+		return fmt.Errorf("unknown format %q, expected seeds, persistent_peers, csv or json", format)
+	}
+}
+
+func formatPeerTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ReadPeers parses id@host:port addresses from r in format.  The seeds and
+// persistent_peers formats also accept bare comma or newline separated
+// lists, such as the output of `tinyseed addrbook dump`.
+func ReadPeers(r io.Reader, format string) ([]string, error) {
+	switch format {
+	case PeerFormatSeeds, PeerFormatPersistentPeers:
+		var addrs []string
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			// seeds = "..." as written by export
+			if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+				line = parts[1]
+			}
+			line = strings.Trim(strings.TrimSpace(line), `"`)
+			for _, addr := range strings.Split(line, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					addrs = append(addrs, addr)
+				}
+			}
+		}
+		return addrs, scanner.Err()
+
+	case PeerFormatCSV:
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		var addrs []string
+		for i, record := range records {
+			if len(record) < 3 {
+				return nil, fmt.Errorf("line %d: expected id, host and port", i+1)
+			}
+			if i == 0 && record[0] == peerCSVHeader[0] {
+				continue
+			}
+			addrs = append(addrs, fmt.Sprintf("%s@%s:%s", record[0], record[1], record[2]))
+		}
+		return addrs, nil
+
+	case PeerFormatJSON:
+		var peers []ExportedPeer
+		if err := json.NewDecoder(r).Decode(&peers); err != nil {
+			return nil, err
+		}
+		addrs := make([]string, 0, len(peers))
+		for _, peer := range peers {
+			addrs = append(addrs, peer.Address)
+		}
+		return addrs, nil
+
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected seeds, persistent_peers, csv or json", format)
+	}
+}
+
+// ExportAddrBook returns the entries of the book of cfg and its shards, most
+// recently successful first, or in random order with
+// AddressShuffleOnExport.  goodOnly keeps the addresses tendermint has marked
+// good, and a positive limit keeps the most recently successful of them.
+func ExportAddrBook(cfg Config, goodOnly bool, limit int) ([]*AddrBookEntry, error) {
+	all, err := LoadAddrBookEntries(AddrBookFiles(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*AddrBookEntry, 0, len(all))
+	for _, entry := range all {
+		if goodOnly && entry.BucketType != bucketTypeOld {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastSuccess.After(entries[j].LastSuccess)
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	if cfg.AddressShuffleOnExport {
+		if err := shuffle(len(entries), func(i, j int) {
+			entries[i], entries[j] = entries[j], entries[i]
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// ImportSummary counts the outcomes of ImportAddrBook.  Rejected holds why
+// each rejected address was refused.
+type ImportSummary struct {
+	Added    int
+	Skipped  int
+	Rejected []error
+}
+
+// ImportAddrBook adds addrs to the book of cfg, placing each in its shard.
+// The seed must not be running, or it overwrites the result when it saves.
+func ImportAddrBook(cfg Config, addrs []string) (ImportSummary, error) {
+	var summary ImportSummary
+	if err := os.MkdirAll(filepath.Dir(cfg.AddrBookFile), os.ModePerm); err != nil {
+		return summary, err
+	}
+
+	// let tendermint place the addresses in its buckets, and the balancer
+	// in their shards
+	book, err := openAddrBook(cfg)
+	if err != nil {
+		return summary, err
+	}
+	book.SetLogger(log.NewNopLogger())
+	if err := book.Start(); err != nil {
+		return summary, err
+	}
+
+	for _, s := range addrs {
+		addr, err := p2p.NewNetAddressString(s)
+		if err != nil {
+			summary.Rejected = append(summary.Rejected, fmt.Errorf("%s: %w", s, err))
+			continue
+		}
+		if book.HasAddress(addr) {
+			summary.Skipped++
+			continue
+		}
+		if err := book.AddAddress(addr, addr); err != nil {
+			summary.Rejected = append(summary.Rejected, fmt.Errorf("%s: %w", s, err))
+			continue
+		}
+		summary.Added++
+	}
+
+	if err := book.Stop(); err != nil {
+		return summary, err
+	}
+	waitAddrBook(book)
+	for _, path := range AddrBookFiles(cfg) {
+		if err := publishAddrBook(path); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,75 @@
+package seed
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// Results reported by POST /api/addrbook/add
+const (
+	AddResultAdded    = "added"
+	AddResultSkipped  = "skipped"
+	AddResultRejected = "rejected"
+)
+
+// AddAddressRequest is the body of POST /api/addrbook/add
+type AddAddressRequest struct {
+	Address string `json:"address"`
+}
+
+// AddAddressResponse is the reply to POST /api/addrbook/add
+type AddAddressResponse struct {
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// StartAdminServer serves the admin API on addr.  It has no authentication,
+// so addr should only be reachable by operators.
+func StartAdminServer(addr string, book pex.AddrBook, logger log.Logger) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/addrbook/add", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req AddAddressRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAddAddressResponse(w, http.StatusBadRequest, AddAddressResponse{Result: AddResultRejected, Error: err.Error()})
+			return
+		}
+		addr, err := p2p.NewNetAddressString(req.Address)
+		if err != nil {
+			writeAddAddressResponse(w, http.StatusBadRequest, AddAddressResponse{Result: AddResultRejected, Error: err.Error()})
+			return
+		}
+		if book.HasAddress(addr) {
+			writeAddAddressResponse(w, http.StatusConflict, AddAddressResponse{Result: AddResultSkipped})
+			return
+		}
+		add := book.AddAddress
+		if b, ok := book.(*seedBook); ok {
+			// skip the write queue so the response says what the book did
+			add = b.addAddress
+		}
+		if err := add(addr, addr); err != nil {
+			writeAddAddressResponse(w, http.StatusBadRequest, AddAddressResponse{Result: AddResultRejected, Error: err.Error()})
+			return
+		}
+
+		logger.Info("address added via admin API", "addr", addr, "from", r.RemoteAddr)
+		writeAddAddressResponse(w, http.StatusCreated, AddAddressResponse{Result: AddResultAdded})
+	})
+	return serveHTTP(addr, mux)
+}
+
+func writeAddAddressResponse(w http.ResponseWriter, status int, resp AddAddressResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
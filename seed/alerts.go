@@ -0,0 +1,77 @@
+package seed
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+)
+
+// AlertThresholds holds the values baked into the generated alerting rules
+type AlertThresholds struct {
+	WarnPeersBelow     int
+	CritPeersBelow     int
+	MaxPeerChurn       int
+	ChurnWindow        time.Duration
+	UnreachableFor     time.Duration
+	StagnationWindow   time.Duration
+	MetricsJobSelector string
+}
+
+// alertsTemplate renders prometheus alerting rules for the seed metrics
+var alertsTemplate = template.Must(template.New("alerts").Funcs(template.FuncMap{
+	"promDuration": promDuration,
+}).Parse(`groups:
+  - name: tinyseed
+    rules:
+      - alert: TinySeedPeersLow
+        expr: tinyseed_p2p_peers < {{ .WarnPeersBelow }}
+        for: 10m
+        labels:
+          severity: warning
+        annotations:
+          summary: "tinyseed {{ "{{" }} $labels.chain_id {{ "}}" }} has fewer than {{ .WarnPeersBelow }} peers"
+      - alert: TinySeedPeersCritical
+        expr: tinyseed_p2p_peers < {{ .CritPeersBelow }}
+        for: 5m
+        labels:
+          severity: critical
+        annotations:
+          summary: "tinyseed {{ "{{" }} $labels.chain_id {{ "}}" }} has fewer than {{ .CritPeersBelow }} peers"
+      - alert: TinySeedPeerChurnHigh
+        expr: changes(tinyseed_p2p_peers[{{ promDuration .ChurnWindow }}]) > {{ .MaxPeerChurn }}
+        labels:
+          severity: warning
+        annotations:
+          summary: "tinyseed {{ "{{" }} $labels.chain_id {{ "}}" }} peer count changed more than {{ .MaxPeerChurn }} times in {{ promDuration .ChurnWindow }}"
+      - alert: TinySeedUnreachable
+        expr: up{ {{- .MetricsJobSelector -}} } == 0
+        for: {{ promDuration .UnreachableFor }}
+        labels:
+          severity: critical
+        annotations:
+          summary: "tinyseed {{ "{{" }} $labels.instance {{ "}}" }} cannot be scraped"
+      - alert: TinySeedAddrBookStagnant
+        expr: delta(tinyseed_addrbook_size[{{ promDuration .StagnationWindow }}]) == 0
+        labels:
+          severity: warning
+        annotations:
+          summary: "tinyseed {{ "{{" }} $labels.chain_id {{ "}}" }} address book has not changed in {{ promDuration .StagnationWindow }}"
+`))
+
+// WriteAlertRules renders the alerting rules for thresholds to w
+func WriteAlertRules(w io.Writer, thresholds AlertThresholds) error {
+	return alertsTemplate.Execute(w, thresholds)
+}
+
+// promDuration formats d the way prometheus expects durations
+func promDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
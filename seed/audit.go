@@ -0,0 +1,167 @@
+package seed
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// cryptoAuditSweepInterval is how often handshakes that never completed are
+// written out as failures
+const cryptoAuditSweepInterval = 10 * time.Second
+
+// CryptoAuditRecord is a line of the crypto audit log.  It identifies peers
+// by node ID only; keys never appear in it.  Failures have no duration, as
+// it is not known when the handshake gave up.
+type CryptoAuditRecord struct {
+	Time        time.Time `json:"time"`
+	RemoteIP    string    `json:"remote_ip"`
+	Direction   string    `json:"direction"`
+	PresentedID p2p.ID    `json:"presented_id,omitempty"`
+	ExpectedID  p2p.ID    `json:"expected_id,omitempty"`
+	Result      string    `json:"result"`
+	Reason      string    `json:"reason,omitempty"`
+	DurationMs  int64     `json:"duration_ms,omitempty"`
+}
+
+// Results of a CryptoAuditRecord
+const (
+	CryptoAuditSuccess = "success"
+	CryptoAuditFailure = "failure"
+)
+
+// pendingHandshake is a connection whose handshake has started
+type pendingHandshake struct {
+	started   time.Time
+	remoteIP  string
+	direction string
+}
+
+// cryptoAudit writes a CryptoAuditRecord for every handshake.  Like
+// handshakeTimer it brackets the handshake between the transport's
+// connection filter and the switch's peer filter.  The transport does not
+// report why a handshake failed, so connections that never reach the peer
+// filter are logged as failures once handshakeTimerExpiry has passed.
+type cryptoAudit struct {
+	listenPort uint16
+	logger     log.Logger
+
+	// mtx serializes writes so that each record is a single write(2)
+	mtx     sync.Mutex
+	out     *os.File
+	pending map[string]pendingHandshake
+}
+
+// newCryptoAudit appends records to the JSONL file at path
+func newCryptoAudit(path string, listenPort uint16, logger log.Logger) (*cryptoAudit, error) {
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &cryptoAudit{
+		listenPort: listenPort,
+		logger:     logger,
+		out:        out,
+		pending:    make(map[string]pendingHandshake),
+	}, nil
+}
+
+// FilterConn is a p2p.ConnFilterFunc noting when the handshake starts
+func (a *cryptoAudit) FilterConn(_ p2p.ConnSet, c net.Conn, _ []net.IP) error {
+	h := pendingHandshake{started: time.Now(), direction: "outbound"}
+	if local, ok := c.LocalAddr().(*net.TCPAddr); ok && local.Port == int(a.listenPort) {
+		h.direction = "inbound"
+	}
+	if remote, ok := c.RemoteAddr().(*net.TCPAddr); ok {
+		h.remoteIP = remote.IP.String()
+	}
+
+	a.mtx.Lock()
+	a.pending[c.RemoteAddr().String()] = h
+	a.mtx.Unlock()
+	return nil
+}
+
+// FilterPeer is a p2p.PeerFilterFunc logging the completed handshake.  It
+// never rejects.
+func (a *cryptoAudit) FilterPeer(_ p2p.IPeerSet, peer p2p.Peer) error {
+	record := CryptoAuditRecord{
+		Time:        time.Now(),
+		RemoteIP:    peer.RemoteIP().String(),
+		Direction:   direction(peer),
+		PresentedID: peer.ID(),
+		Result:      CryptoAuditSuccess,
+	}
+	if peer.IsOutbound() {
+		// the transport already refused the peer unless it presented the
+		// ID we dialed
+		record.ExpectedID = peer.SocketAddr().ID
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	addr := peer.SocketAddr().DialString()
+	if h, ok := a.pending[addr]; ok {
+		record.DurationMs = record.Time.Sub(h.started).Milliseconds()
+		delete(a.pending, addr)
+	}
+	a.write(record)
+	return nil
+}
+
+// Run logs handshakes that never completed until quit is closed
+func (a *cryptoAudit) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(cryptoAuditSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.sweep()
+		case <-quit:
+			a.mtx.Lock()
+			a.out.Close()
+			a.mtx.Unlock()
+			return
+		}
+	}
+}
+
+// sweep logs a failure for every handshake pending longer than
+// handshakeTimerExpiry
+func (a *cryptoAudit) sweep() {
+	now := time.Now()
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	for addr, h := range a.pending {
+		if now.Sub(h.started) <= handshakeTimerExpiry {
+			continue
+		}
+		delete(a.pending, addr)
+		a.write(CryptoAuditRecord{
+			Time:      h.started,
+			RemoteIP:  h.remoteIP,
+			Direction: h.direction,
+			Result:    CryptoAuditFailure,
+			Reason:    "handshake did not complete: authentication failed, timed out or the connection was dropped",
+		})
+	}
+}
+
+// write appends record as one line.  The caller holds mtx.
+func (a *cryptoAudit) write(record CryptoAuditRecord) {
+	bz, err := json.Marshal(record)
+	if err != nil {
+		a.logger.Error("failed to encode crypto audit record", "err", err)
+		return
+	}
+	if _, err := a.out.Write(append(bz, '\n')); err != nil {
+		a.logger.Error("failed to write crypto audit record", "err", err)
+	}
+}
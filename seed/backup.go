@@ -0,0 +1,103 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/tempfile"
+)
+
+// addrBookBackupTimeFormat names backups so that they sort by age
+const addrBookBackupTimeFormat = "20060102T150405.000Z"
+
+// AddrBookFiles lists the files the address book of cfg is stored in, one
+// per shard
+func AddrBookFiles(cfg Config) []string {
+	n := cfg.AddrBookShardCount
+	if n < 1 {
+		n = 1
+	}
+	files := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		files = append(files, shardFilePath(cfg.AddrBookFile, i))
+	}
+	return files
+}
+
+// addrBookBackups lists the backups of the book at path, newest first
+func addrBookBackups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".bak.*")
+	if err != nil {
+		return nil, err
+	}
+	backups := matches[:0]
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".sha256") {
+			backups = append(backups, match)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// BackupAddrBook copies the book at path to a timestamped backup with its
+// own sidecar and deletes all but the keep newest backups.  A book that
+// fails its integrity check is not backed up.
+func BackupAddrBook(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	if err := checkAddrBookFile(path); err != nil {
+		return err
+	}
+	bz, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	backup := path + ".bak." + time.Now().UTC().Format(addrBookBackupTimeFormat)
+	if err := tempfile.WriteFileAtomic(backup, bz, 0644); err != nil {
+		return err
+	}
+	if err := WriteAddrBookHash(backup); err != nil {
+		return err
+	}
+
+	backups, err := addrBookBackups(path)
+	if err != nil {
+		return err
+	}
+	for i, old := range backups {
+		if i < keep {
+			continue
+		}
+		if err := os.Remove(old); err != nil {
+			return err
+		}
+		if err := os.Remove(addrBookHashFile(old)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveEvery saves the book every interval until quit is closed
+func (b *seedBook) saveEvery(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.Save()
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package seed
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+	tmp2p "github.com/tendermint/tendermint/proto/tendermint/p2p"
+)
+
+// seedSelectionBias is the bias towards new addresses tendermint's seed mode
+// uses when answering PEX requests
+const seedSelectionBias = 30
+
+// SeedBalancer spreads the address book over several independent shards so
+// that PEX traffic does not contend on a single book lock.  Addresses live in
+// the shard their ID hashes to, and a PEX request is answered from the shard
+// the requester's ID hashes to.  Operations on a single address are routed to
+// its shard; the rest fan out to every shard.
+//
+// The first shard is embedded and stands in for the others where
+// service.Service only makes sense once, such as IsRunning and Quit.
+type SeedBalancer struct {
+	pex.AddrBook
+
+	shards []pex.AddrBook
+}
+
+var _ pex.AddrBook = (*SeedBalancer)(nil)
+
+// NewSeedBalancer sets up n shards.  Shard 0 keeps AddrBookFile so a
+// single-shard seed uses the same file as before; shard i is stored next to
+// it as addrbook.<i>.json.  Like a single book, the shards save to their
+// working files, and seedBook.Save publishes them.
+func NewSeedBalancer(cfg Config, n int) (*SeedBalancer, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("addr_book_shard_count must be at least 1, got %d", n)
+	}
+	strict, err := cfg.RoutabilityStrict()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &SeedBalancer{}
+	cfg.AddrBookShardCount = n
+	for _, path := range AddrBookFiles(cfg) {
+		b.shards = append(b.shards, pex.NewAddrBook(addrBookWorkingFile(path), strict))
+	}
+	b.AddrBook = b.shards[0]
+	return b, nil
+}
+
+// shardFilePath returns where shard i of the book at path is stored
+func shardFilePath(path string, i int) string {
+	if i == 0 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), i, ext)
+}
+
+// Shard returns the shard responsible for id
+func (b *SeedBalancer) Shard(id p2p.ID) pex.AddrBook {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return b.shards[h.Sum32()%uint32(len(b.shards))]
+}
+
+// Start implements service.Service
+func (b *SeedBalancer) Start() error {
+	for _, shard := range b.shards {
+		if err := shard.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop implements service.Service
+func (b *SeedBalancer) Stop() error {
+	for _, shard := range b.shards {
+		if err := shard.Stop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wait waits for every shard to finish the save it makes when stopping
+func (b *SeedBalancer) Wait() {
+	for _, shard := range b.shards {
+		waitAddrBook(shard)
+	}
+}
+
+// Reset implements service.Service
+func (b *SeedBalancer) Reset() error {
+	for _, shard := range b.shards {
+		if err := shard.Reset(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLogger implements service.Service
+func (b *SeedBalancer) SetLogger(logger log.Logger) {
+	for i, shard := range b.shards {
+		shard.SetLogger(logger.With("shard", i))
+	}
+}
+
+// AddOurAddress implements pex.AddrBook
+func (b *SeedBalancer) AddOurAddress(addr *p2p.NetAddress) {
+	for _, shard := range b.shards {
+		shard.AddOurAddress(addr)
+	}
+}
+
+// AddPrivateIDs implements pex.AddrBook
+func (b *SeedBalancer) AddPrivateIDs(ids []string) {
+	for _, shard := range b.shards {
+		shard.AddPrivateIDs(ids)
+	}
+}
+
+// AddAddress implements pex.AddrBook
+func (b *SeedBalancer) AddAddress(addr *p2p.NetAddress, src *p2p.NetAddress) error {
+	if addr == nil {
+		return pex.ErrAddrBookNilAddr{Addr: addr, Src: src}
+	}
+	return b.Shard(addr.ID).AddAddress(addr, src)
+}
+
+// RemoveAddress implements pex.AddrBook
+func (b *SeedBalancer) RemoveAddress(addr *p2p.NetAddress) {
+	b.Shard(addr.ID).RemoveAddress(addr)
+}
+
+// HasAddress implements pex.AddrBook
+func (b *SeedBalancer) HasAddress(addr *p2p.NetAddress) bool {
+	return b.Shard(addr.ID).HasAddress(addr)
+}
+
+// NeedMoreAddrs implements pex.AddrBook
+func (b *SeedBalancer) NeedMoreAddrs() bool {
+	for _, shard := range b.shards {
+		if shard.NeedMoreAddrs() {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty implements pex.AddrBook
+func (b *SeedBalancer) Empty() bool {
+	for _, shard := range b.shards {
+		if !shard.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// PickAddress implements pex.AddrBook, trying the shards in random order
+func (b *SeedBalancer) PickAddress(biasTowardsNewAddrs int) *p2p.NetAddress {
+	for _, i := range tmrand.Perm(len(b.shards)) {
+		if addr := b.shards[i].PickAddress(biasTowardsNewAddrs); addr != nil {
+			return addr
+		}
+	}
+	return nil
+}
+
+// MarkGood implements pex.AddrBook
+func (b *SeedBalancer) MarkGood(id p2p.ID) {
+	b.Shard(id).MarkGood(id)
+}
+
+// MarkAttempt implements pex.AddrBook
+func (b *SeedBalancer) MarkAttempt(addr *p2p.NetAddress) {
+	b.Shard(addr.ID).MarkAttempt(addr)
+}
+
+// MarkBad implements pex.AddrBook
+func (b *SeedBalancer) MarkBad(addr *p2p.NetAddress, banTime time.Duration) {
+	b.Shard(addr.ID).MarkBad(addr, banTime)
+}
+
+// ReinstateBadPeers implements pex.AddrBook
+func (b *SeedBalancer) ReinstateBadPeers() {
+	for _, shard := range b.shards {
+		shard.ReinstateBadPeers()
+	}
+}
+
+// IsGood implements pex.AddrBook
+func (b *SeedBalancer) IsGood(addr *p2p.NetAddress) bool {
+	return b.Shard(addr.ID).IsGood(addr)
+}
+
+// IsBanned implements pex.AddrBook
+func (b *SeedBalancer) IsBanned(addr *p2p.NetAddress) bool {
+	return b.Shard(addr.ID).IsBanned(addr)
+}
+
+// GetSelection implements pex.AddrBook.  The seed crawls this selection, so
+// every shard contributes.
+func (b *SeedBalancer) GetSelection() []*p2p.NetAddress {
+	var addrs []*p2p.NetAddress
+	for _, shard := range b.shards {
+		addrs = append(addrs, shard.GetSelection()...)
+	}
+	return addrs
+}
+
+// GetSelectionWithBias implements pex.AddrBook.  Requests that can be tied
+// to a requester are answered from its shard by balancedPexReactor; this
+// fallback draws from a random shard.
+func (b *SeedBalancer) GetSelectionWithBias(biasTowardsNewAddrs int) []*p2p.NetAddress {
+	return b.shards[tmrand.Intn(len(b.shards))].GetSelectionWithBias(biasTowardsNewAddrs)
+}
+
+// Size implements pex.AddrBook
+func (b *SeedBalancer) Size() int {
+	size := 0
+	for _, shard := range b.shards {
+		size += shard.Size()
+	}
+	return size
+}
+
+// Save implements pex.AddrBook
+func (b *SeedBalancer) Save() {
+	for _, shard := range b.shards {
+		shard.Save()
+	}
+}
+
+// balancedPexReactor answers inbound PEX requests itself so that the
+// response can be drawn from the requester's shard or chosen by its
+// location; the tendermint reactor never tells the book who is asking.  Like tendermint's seed mode, it
+// answers once and then disconnects.  Everything else is left to the
+// wrapped reactor.
+type balancedPexReactor struct {
+	p2p.Reactor
+
+	book *seedBook
+
+	mtx      sync.Mutex
+	sw       *p2p.Switch
+	answered map[p2p.ID]struct{}
+}
+
+func newBalancedPexReactor(reactor p2p.Reactor, book *seedBook) *balancedPexReactor {
+	return &balancedPexReactor{
+		Reactor:  reactor,
+		book:     book,
+		answered: make(map[p2p.ID]struct{}),
+	}
+}
+
+// SetSwitch implements p2p.Reactor
+func (r *balancedPexReactor) SetSwitch(sw *p2p.Switch) {
+	r.mtx.Lock()
+	r.sw = sw
+	r.mtx.Unlock()
+	r.Reactor.SetSwitch(sw)
+}
+
+// RemovePeer implements p2p.Reactor
+func (r *balancedPexReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	r.mtx.Lock()
+	delete(r.answered, peer.ID())
+	r.mtx.Unlock()
+	r.Reactor.RemovePeer(peer, reason)
+}
+
+// Receive implements p2p.Reactor
+func (r *balancedPexReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
+	var msg tmp2p.Message
+	if src.IsOutbound() || msg.Unmarshal(msgBytes) != nil || msg.GetPexRequest() == nil {
+		r.Reactor.Receive(chID, src, msgBytes)
+		return
+	}
+
+	r.mtx.Lock()
+	if _, ok := r.answered[src.ID()]; ok {
+		// already disconnecting
+		r.mtx.Unlock()
+		return
+	}
+	r.answered[src.ID()] = struct{}{}
+	sw := r.sw
+	r.mtx.Unlock()
+
+	addrs := r.book.SelectionFor(src.ID(), src.RemoteIP(), seedSelectionBias)
+	resp := tmp2p.Message{Sum: &tmp2p.Message_PexAddrs{
+		PexAddrs: &tmp2p.PexAddrs{Addrs: p2p.NetAddressesToProto(addrs)},
+	}}
+	bz, err := resp.Marshal()
+	if err != nil {
+		panic(err)
+	}
+	src.Send(pex.PexChannel, bz)
+
+	go func() {
+		// in a goroutine so it doesn't block Receive
+		src.FlushStop()
+		sw.StopPeerGracefully(src)
+	}()
+}
@@ -0,0 +1,118 @@
+package seed
+
+import (
+	"sync"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// addrWriteBatchSize is the most AddAddress calls a worker applies in one go
+const addrWriteBatchSize = 64
+
+// addrWrite is a queued AddAddress call
+type addrWrite struct {
+	addr *p2p.NetAddress
+	src  *p2p.NetAddress
+}
+
+// addrWriter applies AddAddress calls from a pool of workers so callers
+// handling PEX traffic do not queue up on the address book mutex
+type addrWriter struct {
+	add   func(addr, src *p2p.NetAddress)
+	queue chan addrWrite
+	quit  chan struct{}
+	wg    sync.WaitGroup
+
+	// stopped is set under mtx once Stop begins; Add holds a read lock
+	// while it queues, so nothing is queued once the workers drain
+	mtx     sync.RWMutex
+	stopped bool
+}
+
+// startAddrWriter starts workers goroutines that apply queued writes with add
+func startAddrWriter(workers int, add func(addr, src *p2p.NetAddress)) *addrWriter {
+	if workers < 1 {
+		workers = 1
+	}
+
+	w := &addrWriter{
+		add:   add,
+		queue: make(chan addrWrite, workers*addrWriteBatchSize),
+		quit:  make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+
+	return w
+}
+
+func (w *addrWriter) run() {
+	defer w.wg.Done()
+	batch := make([]addrWrite, 0, addrWriteBatchSize)
+	for {
+		select {
+		case write := <-w.queue:
+			batch = append(batch[:0], write)
+		case <-w.quit:
+			w.drain()
+			return
+		}
+
+		// pick up whatever else is already waiting
+	DRAIN:
+		for len(batch) < addrWriteBatchSize {
+			select {
+			case write := <-w.queue:
+				batch = append(batch, write)
+			default:
+				break DRAIN
+			}
+		}
+
+		for _, write := range batch {
+			w.add(write.addr, write.src)
+		}
+	}
+}
+
+// drain applies the writes still queued
+func (w *addrWriter) drain() {
+	for {
+		select {
+		case write := <-w.queue:
+			w.add(write.addr, write.src)
+		default:
+			return
+		}
+	}
+}
+
+// Add queues a write, blocking if the workers are falling behind.  Once
+// the writer is stopped the write is applied directly.
+func (w *addrWriter) Add(addr, src *p2p.NetAddress) {
+	w.mtx.RLock()
+	if w.stopped {
+		w.mtx.RUnlock()
+		w.add(addr, src)
+		return
+	}
+	w.queue <- addrWrite{addr: addr, src: src}
+	w.mtx.RUnlock()
+}
+
+// Stop applies every queued write and stops the workers
+func (w *addrWriter) Stop() {
+	w.mtx.Lock()
+	if w.stopped {
+		w.mtx.Unlock()
+		return
+	}
+	w.stopped = true
+	w.mtx.Unlock()
+
+	close(w.quit)
+	w.wg.Wait()
+}
@@ -0,0 +1,127 @@
+package seed
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// benchmarkClients is how many goroutines add addresses at once, like
+// peers whose PEX responses arrive together
+const benchmarkClients = 1000
+
+// testAddrs returns n distinct addresses spread over many /16 groups
+func testAddrs(n int) []*p2p.NetAddress {
+	addrs := make([]*p2p.NetAddress, n)
+	for i := range addrs {
+		ip := net.IPv4(byte(1+i%200), byte(i/200), byte(i>>8), byte(i))
+		addrs[i] = p2p.NewNetAddressIPPort(ip, 26656)
+		addrs[i].ID = p2p.ID(fmt.Sprintf("%040x", i))
+	}
+	return addrs
+}
+
+// newTestSeedBook returns a seedBook over a non-strict book in a temporary
+// directory, sharded when shards > 1
+func newTestSeedBook(tb testing.TB, shards int, concurrent bool) *seedBook {
+	cfg := DefaultConfig(tb.TempDir())
+	cfg.AddrBookFile = filepath.Join(tb.TempDir(), "addrbook.json")
+	cfg.AddrBookStrict = false
+	cfg.AddrBookShardCount = shards
+	cfg.ConcurrentAddressBookWrites = concurrent
+
+	var book pex.AddrBook
+	if shards > 1 {
+		balancer, err := NewSeedBalancer(*cfg, shards)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		book = balancer
+	} else {
+		book = pex.NewAddrBook(addrBookWorkingFile(cfg.AddrBookFile), false)
+	}
+	book.SetLogger(log.NewNopLogger())
+
+	b, err := newSeedBook(book, *cfg, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+func TestAddrWriterStopAppliesQueuedWrites(t *testing.T) {
+	b := newTestSeedBook(t, 1, true)
+	src := testAddrs(1)[0]
+	addrs := testAddrs(500)[1:]
+
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		go func(addr *p2p.NetAddress) {
+			defer wg.Done()
+			b.AddAddress(addr, src)
+		}(addr)
+	}
+	wg.Wait()
+	b.writer.Stop()
+
+	for _, addr := range addrs {
+		if !b.HasAddress(addr) {
+			t.Fatalf("%v was queued but is not in the book", addr)
+		}
+	}
+
+	// writes after Stop are applied directly
+	late := testAddrs(501)[500]
+	b.AddAddress(late, src)
+	if !b.HasAddress(late) {
+		t.Fatal("a write after Stop was dropped")
+	}
+}
+
+// BenchmarkAddAddress adds b.N addresses from benchmarkClients goroutines,
+// timing until every write has reached the book.  caller-ns/op is the time
+// until every AddAddress call returned.
+func BenchmarkAddAddress(b *testing.B) {
+	for _, bc := range []struct {
+		name       string
+		shards     int
+		concurrent bool
+	}{
+		{"book/direct", 1, false},
+		{"book/queued", 1, true},
+		{"shards4/direct", 4, false},
+		{"shards4/queued", 4, true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			book := newTestSeedBook(b, bc.shards, bc.concurrent)
+			addrs := testAddrs(b.N + 1)
+			src, addrs := addrs[0], addrs[1:]
+
+			b.ResetTimer()
+			start := time.Now()
+			var wg sync.WaitGroup
+			for c := 0; c < benchmarkClients; c++ {
+				wg.Add(1)
+				go func(c int) {
+					defer wg.Done()
+					for i := c; i < len(addrs); i += benchmarkClients {
+						book.AddAddress(addrs[i], src)
+					}
+				}(c)
+			}
+			wg.Wait()
+			b.ReportMetric(float64(time.Since(start).Nanoseconds())/float64(b.N), "caller-ns/op")
+			if book.writer != nil {
+				book.writer.Stop()
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ChainConfig is a network served alongside others by the same process.
+// Settings left empty are derived from the top-level config.
+type ChainConfig struct {
+	ChainID       string `toml:"chain_id" comment:"network identifier"`
+	ListenAddress string `toml:"laddr" comment:"Address to listen for incoming connections, distinct for every chain"`
+	Seeds         string `toml:"seeds" comment:"seed nodes we can use to discover peers"`
+	SeedsURL      string `toml:"seeds_url" comment:"URL of this chain's chain.json or plain text seed list, merged with seeds"`
+	AddrBookFile  string `toml:"addr_book_file" comment:"path to address book (defaults to <chain_id>/ next to the top-level addr_book_file)"`
+	NodeKeyFile   string `toml:"node_key_file" comment:"path to node_key (defaults to <chain_id>/ next to the top-level node_key_file)"`
+	P2PVersion    uint64 `toml:"p2p_version" comment:"P2P protocol version advertised on this chain (defaults to the top-level p2p_version)"`
+	BlockVersion  uint64 `toml:"block_version" comment:"Block protocol version advertised on this chain (defaults to the top-level block_version)"`
+}
+
+// ChainConfigs returns the config of each entry of c.Chains.  Every chain
+// shares the top-level settings, with the files a seed writes to moved into
+// a directory per chain.
+func (c Config) ChainConfigs() ([]Config, error) {
+	// these bind a single port or mix every chain's addresses together
+	if c.AdminListenAddress != "" {
+		return nil, errors.New("admin_listen_address cannot be used with chains")
+	}
+	if c.UDPDiscovery {
+		return nil, errors.New("udp_discovery cannot be used with chains")
+	}
+
+	seen := make(map[string]bool)
+	configs := make([]Config, 0, len(c.Chains))
+	for i, chain := range c.Chains {
+		if chain.ChainID == "" || chain.ListenAddress == "" {
+			return nil, fmt.Errorf("chains[%d]: chain_id and laddr are required", i)
+		}
+		if seen[chain.ChainID] {
+			return nil, fmt.Errorf("chains[%d]: chain %q is listed twice", i, chain.ChainID)
+		}
+		seen[chain.ChainID] = true
+
+		cfg := c
+		cfg.Chains = nil
+		cfg.ChainID = chain.ChainID
+		cfg.ListenAddress = chain.ListenAddress
+		cfg.Seeds = chain.Seeds
+		cfg.SeedsURL = chain.SeedsURL
+		cfg.ExternalAddress = ""
+		cfg.NodeKeyFile = chainFilePath(c.NodeKeyFile, chain.ChainID)
+		if chain.NodeKeyFile != "" {
+			cfg.NodeKeyFile = chain.NodeKeyFile
+		}
+		cfg.AddrBookFile = chainFilePath(c.AddrBookFile, chain.ChainID)
+		if chain.AddrBookFile != "" {
+			cfg.AddrBookFile = chain.AddrBookFile
+		}
+		if chain.P2PVersion != 0 {
+			cfg.P2PVersion = chain.P2PVersion
+		}
+		if chain.BlockVersion != 0 {
+			cfg.BlockVersion = chain.BlockVersion
+		}
+		cfg.AccessLogFile = chainFilePath(c.AccessLogFile, chain.ChainID)
+		cfg.ExportOnShutdownFile = chainFilePath(c.ExportOnShutdownFile, chain.ChainID)
+		cfg.GracefulRestartFile = chainFilePath(c.GracefulRestartFile, chain.ChainID)
+		cfg.CryptoAuditLog = chainFilePath(c.CryptoAuditLog, chain.ChainID)
+		cfg.PeerHistoryFile = chainFilePath(c.PeerHistoryFile, chain.ChainID)
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// chainFilePath moves path into a directory named after chainID.  Unset
+// paths stay unset.
+func chainFilePath(path, chainID string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), chainID, filepath.Base(path))
+}
@@ -0,0 +1,81 @@
+package seed
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// ChannelPriorities maps channel IDs to MConn send priorities.  TOML keys
+// are always strings, so it decodes them itself.
+type ChannelPriorities map[byte]int
+
+// UnmarshalTOML implements toml.Unmarshaler
+func (p *ChannelPriorities) UnmarshalTOML(v interface{}) error {
+	table, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("channel priorities must be a table, got %T", v)
+	}
+
+	priorities := make(ChannelPriorities, len(table))
+	for key, value := range table {
+		chID, err := strconv.ParseUint(key, 0, 8)
+		if err != nil {
+			return fmt.Errorf("invalid channel ID %q: %w", key, err)
+		}
+		priority, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("channel %s: priority must be an integer, got %T", key, value)
+		}
+		priorities[byte(chID)] = int(priority)
+	}
+	*p = priorities
+	return nil
+}
+
+// checkChannelPriorities makes sure priorities only names channels the seed
+// serves, so that a typo does not go unnoticed
+func checkChannelPriorities(priorities map[byte]int, channels []byte) error {
+	for chID := range priorities {
+		if !bytes.Contains(channels, []byte{chID}) {
+			return fmt.Errorf("channel_priority_map: the seed does not serve channel %#x", chID)
+		}
+	}
+	return nil
+}
+
+// prioritizedReactor overrides the MConn send priorities of the channels a
+// reactor registers with the switch
+type prioritizedReactor struct {
+	p2p.Reactor
+
+	priorities map[byte]int
+}
+
+// WithChannelPriorities wraps reactor so that its channels use the priorities
+// from ChannelPriorityMap.  Channels without an entry keep their default.
+func WithChannelPriorities(reactor p2p.Reactor, priorities map[byte]int) (p2p.Reactor, error) {
+	for chID, priority := range priorities {
+		if priority <= 0 {
+			return nil, fmt.Errorf("channel %#x: priority must be positive, got %d", chID, priority)
+		}
+	}
+	if len(priorities) == 0 {
+		return reactor, nil
+	}
+	return &prioritizedReactor{Reactor: reactor, priorities: priorities}, nil
+}
+
+// GetChannels implements p2p.Reactor
+func (r *prioritizedReactor) GetChannels() []*conn.ChannelDescriptor {
+	chDescs := r.Reactor.GetChannels()
+	for _, chDesc := range chDescs {
+		if priority, ok := r.priorities[chDesc.ID]; ok {
+			chDesc.Priority = priority
+		}
+	}
+	return chDescs
+}
@@ -0,0 +1,141 @@
+package seed
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// channelsReactor registers the given channels and nothing else
+type channelsReactor struct {
+	p2p.BaseReactor
+
+	chDescs []*conn.ChannelDescriptor
+}
+
+func (r *channelsReactor) GetChannels() []*conn.ChannelDescriptor {
+	return r.chDescs
+}
+
+func TestChannelPrioritiesSendOrder(t *testing.T) {
+	const (
+		highCh  = byte(0x00)
+		lowCh   = MempoolChannel
+		stallCh = byte(0x99)
+		msgs    = 20
+	)
+
+	reactor, err := WithChannelPriorities(&channelsReactor{chDescs: []*conn.ChannelDescriptor{
+		{ID: lowCh, Priority: 1, SendQueueCapacity: msgs, RecvMessageCapacity: 1 << 20},
+		{ID: highCh, Priority: 1, SendQueueCapacity: msgs, RecvMessageCapacity: 1 << 20},
+		{ID: stallCh, Priority: 1, SendQueueCapacity: 1, RecvMessageCapacity: 1 << 20},
+	}}, map[byte]int{highCh: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chDescs := reactor.GetChannels()
+
+	cfg := conn.DefaultMConnConfig()
+	cfg.SendRate = 50 * 1024 * 1024
+	cfg.RecvRate = 50 * 1024 * 1024
+
+	var mtx sync.Mutex
+	var received []byte
+	done := make(chan struct{})
+	onReceive := func(chID byte, msgBytes []byte) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if chID == stallCh {
+			return
+		}
+		received = append(received, chID)
+		if len(received) == 2*msgs {
+			close(done)
+		}
+	}
+	// closing the pipe at the end fails both connections, so errors are only
+	// looked at while waiting for the messages
+	errs := make(chan interface{}, 2)
+	onError := func(r interface{}) {
+		select {
+		case errs <- r:
+		default:
+		}
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	sender := conn.NewMConnectionWithConfig(client, chDescs, func(byte, []byte) {}, onError, cfg)
+	sender.SetLogger(log.NewNopLogger())
+	receiver := conn.NewMConnectionWithConfig(server, chDescs, onReceive, onError, cfg)
+	receiver.SetLogger(log.NewNopLogger())
+
+	if err := sender.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Stop()
+
+	// nobody reads the pipe yet, so once this message has filled the write
+	// buffer the send routine is stuck until both queues below are full
+	if !sender.Send(stallCh, make([]byte, 256*1024)) {
+		t.Fatal("failed to queue the stalling message")
+	}
+	for deadline := time.Now().Add(10 * time.Second); sender.Status().SendMonitor.Bytes < 32*1024; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the send routine to stall")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	msg := make([]byte, 1000)
+	for i := 0; i < msgs; i++ {
+		if !sender.Send(lowCh, msg) || !sender.Send(highCh, msg) {
+			t.Fatal("failed to queue a message")
+		}
+	}
+
+	if err := receiver.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	select {
+	case <-done:
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the messages")
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	lastHigh := bytes.LastIndexByte(received, highCh)
+	lowBefore := bytes.Count(received[:lastHigh], []byte{lowCh})
+	// at ten times the priority the high channel sends ten messages for
+	// every low one; with equal priorities they would alternate
+	if lowBefore > msgs/10+1 {
+		t.Errorf("%d low priority messages were sent before the last high priority one: %v", lowBefore, received)
+	}
+}
+
+func TestWithChannelPrioritiesRejectsNonPositive(t *testing.T) {
+	if _, err := WithChannelPriorities(newMempoolReactor(), map[byte]int{MempoolChannel: 0}); err == nil {
+		t.Error("expected an error for priority 0")
+	}
+}
+
+func TestCheckChannelPriorities(t *testing.T) {
+	channels := []byte{0x00, MempoolChannel}
+	if err := checkChannelPriorities(map[byte]int{0x00: 10, MempoolChannel: 1}, channels); err != nil {
+		t.Error(err)
+	}
+	if err := checkChannelPriorities(map[byte]int{0x20: 10}, channels); err == nil {
+		t.Error("expected an error for a channel the seed does not serve")
+	}
+}
@@ -0,0 +1,71 @@
+package seed
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml"
+	"github.com/tendermint/tendermint/libs/tempfile"
+)
+
+// LoadConfig reads the config at path on top of defaults.  If there is no
+// config at path, the encrypted config next to it is read instead, and if
+// neither exists defaults are written to path first so that there is a file
+// to edit.  Relative file settings are resolved against homeDir.  Only
+// ConfigVersion is not taken from defaults: it is empty unless the file
+// sets it.
+func LoadConfig(path, homeDir string, defaults Config) (Config, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(path + ".enc"); err == nil {
+			path += ".enc"
+		} else if err := WriteConfigFile(path, defaults); err != nil {
+			return defaults, err
+		}
+	}
+
+	data, err := ReadConfigFile(path)
+	if err != nil {
+		return defaults, err
+	}
+	// the version comes from the file alone, so that a file without one is
+	// reported as predating versioning
+	base := defaults
+	base.ConfigVersion = ""
+	cfg, err := ParseConfig(data, base)
+	if err != nil {
+		return defaults, fmt.Errorf("%s: %w", path, err)
+	}
+
+	cfg.ResolvePaths(homeDir)
+	return cfg, nil
+}
+
+// ResolvePaths makes the file settings documented as relative to the home
+// directory absolute
+func (c *Config) ResolvePaths(homeDir string) {
+	paths := []*string{&c.NodeKeyFile, &c.AddrBookFile, &c.AccessLogFile, &c.PeerHistoryFile}
+	for i := range c.Chains {
+		paths = append(paths, &c.Chains[i].NodeKeyFile, &c.Chains[i].AddrBookFile)
+	}
+	for _, path := range paths {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(homeDir, *path)
+		}
+	}
+}
+
+// WriteConfigFile writes cfg to path as TOML.  Settings are sorted by key:
+// keeping declaration order would put plain keys after the map settings'
+// tables, where they would be read back as part of the table.
+func WriteConfigFile(path string, cfg Config) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return tempfile.WriteFileAtomic(path, buf.Bytes(), 0644)
+}
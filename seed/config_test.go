@@ -0,0 +1,44 @@
+package seed
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestLoadConfigWithoutVersionIsOutdated(t *testing.T) {
+	home := t.TempDir()
+	path := filepath.Join(home, "config.toml")
+	if err := os.WriteFile(path, []byte("chain_id = \"test\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path, home, *DefaultConfig(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ConfigVersion != "" {
+		t.Fatalf("config without config_version loaded as version %q", cfg.ConfigVersion)
+	}
+	var buf bytes.Buffer
+	CheckConfigVersion(cfg, log.NewTMLogger(&buf))
+	if !strings.Contains(buf.String(), "config file is outdated") {
+		t.Fatalf("no outdated config warning, logged %q", buf.String())
+	}
+}
+
+func TestLoadConfigWrittenDefaultsAreCurrent(t *testing.T) {
+	home := t.TempDir()
+	path := filepath.Join(home, "config", "config.toml")
+	cfg, err := LoadConfig(path, home, *DefaultConfig(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ConfigVersion != CurrentConfigVersion {
+		t.Fatalf("freshly written config loaded as version %q, want %q", cfg.ConfigVersion, CurrentConfigVersion)
+	}
+}
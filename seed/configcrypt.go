@@ -0,0 +1,121 @@
+package seed
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ConfigPassphraseEnv names the variable holding the passphrase for an
+// encrypted config file
+const ConfigPassphraseEnv = "CONFIG_PASSPHRASE"
+
+// encryptedConfigMagic prefixes every encrypted config file
+var encryptedConfigMagic = []byte("tinyseed-enc-v1\n")
+
+const (
+	configSaltSize = 16
+	configKeySize  = 32 // AES-256
+
+	// scrypt parameters recommended for interactive use
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// EncryptConfig seals plaintext with AES-256-GCM using a key derived from
+// passphrase
+func EncryptConfig(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, configSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := configCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedConfigMagic)+len(salt)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, encryptedConfigMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, encryptedConfigMagic), nil
+}
+
+// DecryptConfig opens data produced by EncryptConfig
+func DecryptConfig(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncryptedConfig(data) {
+		return nil, errors.New("not an encrypted tinyseed config")
+	}
+	data = data[len(encryptedConfigMagic):]
+	if len(data) < configSaltSize {
+		return nil, errors.New("encrypted config is truncated")
+	}
+	salt, data := data[:configSaltSize], data[configSaltSize:]
+
+	gcm, err := configCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted config is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, encryptedConfigMagic)
+	if err != nil {
+		return nil, errors.New("failed to decrypt config: wrong passphrase or corrupted file")
+	}
+	return plaintext, nil
+}
+
+// IsEncryptedConfig reports whether data was produced by EncryptConfig
+func IsEncryptedConfig(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedConfigMagic)
+}
+
+// configCipher derives the AES-GCM cipher for passphrase and salt
+func configCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, configKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadConfigFile reads the config file at path, transparently decrypting it
+// with the passphrase from CONFIG_PASSPHRASE if it is encrypted
+func ReadConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncryptedConfig(data) {
+		return data, nil
+	}
+
+	passphrase, ok := os.LookupEnv(ConfigPassphraseEnv)
+	if !ok {
+		return nil, fmt.Errorf("%s is encrypted but %s is not set", path, ConfigPassphraseEnv)
+	}
+	return DecryptConfig(data, passphrase)
+}
@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"strconv"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// CurrentConfigVersion is bumped whenever the config schema changes in a way
+// that needs operators to revisit their config file
+const CurrentConfigVersion = "1"
+
+// legacyConfigVersion is assumed for config files that predate ConfigVersion
+const legacyConfigVersion = "0"
+
+// CheckConfigVersion logs when the config was written for a different schema
+// than this build expects
+func CheckConfigVersion(SeedConfig Config, logger log.Logger) {
+	version := SeedConfig.ConfigVersion
+	if version == "" {
+		version = legacyConfigVersion
+	}
+
+	switch compareConfigVersions(version, CurrentConfigVersion) {
+	case -1:
+		logger.Info("config file is outdated",
+			"config_version", version,
+			"expected", CurrentConfigVersion,
+			"upgrade", "review the release notes for config changes since version "+version+", then set config_version = \""+CurrentConfigVersion+"\"",
+		)
+	case 1:
+		logger.Error("config file is newer than this tinyseed build",
+			"config_version", version,
+			"expected", CurrentConfigVersion,
+		)
+	}
+}
+
+// compareConfigVersions compares two config versions numerically, falling
+// back to a plain string comparison for non-numeric versions
+func compareConfigVersions(a, b string) int {
+	ai, aErr := strconv.Atoi(a)
+	bi, bErr := strconv.Atoi(b)
+	if aErr != nil || bErr != nil {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+
+	switch {
+	case ai < bi:
+		return -1
+	case ai > bi:
+		return 1
+	}
+	return 0
+}
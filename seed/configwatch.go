@@ -0,0 +1,74 @@
+package seed
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// ConfigChange is a single setting that differs between two configs
+type ConfigChange struct {
+	Field string
+	Old   interface{}
+	New   interface{}
+}
+
+// String formats the change as "field: old -> new"
+func (c ConfigChange) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Field, c.Old, c.New)
+}
+
+// Diff returns the settings that changed from c to other, named by their
+// toml keys
+func (c Config) Diff(other Config) []ConfigChange {
+	var changes []ConfigChange
+
+	oldVal := reflect.ValueOf(c)
+	newVal := reflect.ValueOf(other)
+	for i := 0; i < oldVal.NumField(); i++ {
+		oldField, newField := oldVal.Field(i), newVal.Field(i)
+		if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+
+		field := oldVal.Type().Field(i)
+		name := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if name == "-" {
+			// not a setting
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		changes = append(changes, ConfigChange{
+			Field: name,
+			Old:   displayValue(oldField),
+			New:   displayValue(newField),
+		})
+	}
+	return changes
+}
+
+// displayValue dereferences optional settings so that diffs show values
+// rather than pointers
+func displayValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "unset"
+		}
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
+
+// ParseConfig decodes a TOML config on top of base, so that settings missing
+// from data keep their value from base
+func ParseConfig(data []byte, base Config) (Config, error) {
+	cfg := base
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return base, err
+	}
+	return cfg, nil
+}
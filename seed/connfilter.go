@@ -0,0 +1,24 @@
+package seed
+
+import (
+	"net"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// sequentialConnFilter runs filters one after another, stopping at the first
+// that rejects the connection.  The transport runs each of its filters on
+// its own goroutine, so without this a filter that takes something from a
+// shared budget, such as a handshake token, would spend it on connections
+// another filter rejects.  Filters that reject should come first and those
+// that consume or record last.
+func sequentialConnFilter(filters ...p2p.ConnFilterFunc) p2p.ConnFilterFunc {
+	return func(cs p2p.ConnSet, c net.Conn, ips []net.IP) error {
+		for _, filter := range filters {
+			if err := filter(cs, c, ips); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package seed
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+func TestRejectedConnectionsSpendNoHandshakeTokens(t *testing.T) {
+	// one token, and no new one within the test
+	limiter, err := newHandshakeLimiter(0.001, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := true
+	reject := func(p2p.ConnSet, net.Conn, []net.IP) error {
+		if scanner {
+			return errors.New("blocked")
+		}
+		return nil
+	}
+	filter := sequentialConnFilter(reject, limiter.FilterConn)
+
+	c, other := net.Pipe()
+	defer c.Close()
+	defer other.Close()
+	for i := 0; i < 5; i++ {
+		if err := filter(nil, c, nil); err == nil {
+			t.Fatal("a rejected connection was let through")
+		}
+	}
+	scanner = false
+	if err := filter(nil, c, nil); err != nil {
+		t.Fatalf("rejected connections used up the handshake token: %v", err)
+	}
+}
+
+// acceptedConn returns the server side of a loopback connection to ln
+func acceptedConn(t *testing.T, ln net.Listener) net.Conn {
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestRejectedConnectionsHoldNoHandshakeSlots(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := uint16(ln.Addr().(*net.TCPAddr).Port)
+
+	throttle, err := newInboundThrottle(port, 0, 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := true
+	reject := func(p2p.ConnSet, net.Conn, []net.IP) error {
+		if rejected {
+			return errors.New("queue timeout")
+		}
+		return nil
+	}
+	filter := sequentialConnFilter(throttle.FilterConn, reject, throttle.StartHandshake)
+
+	for i := 0; i < 3; i++ {
+		if err := filter(nil, acceptedConn(t, ln), nil); err == nil {
+			t.Fatal("a rejected connection was let through")
+		}
+	}
+	rejected = false
+	if err := filter(nil, acceptedConn(t, ln), nil); err != nil {
+		t.Fatalf("rejected connections hold the only handshake slot: %v", err)
+	}
+	if err := filter(nil, acceptedConn(t, ln), nil); err == nil {
+		t.Fatal("max_concurrent_handshakes was exceeded")
+	}
+}
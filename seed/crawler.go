@@ -0,0 +1,167 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// crawlerProbeTimeout bounds each step of a liveness probe
+const crawlerProbeTimeout = 10 * time.Second
+
+// ProbePeer checks that addr is alive: it accepts a connection, completes
+// the secret connection handshake as the ID in addr and is on chainID.  It
+// returns how long that took.  protocol is the version we claim to speak.
+func ProbePeer(addr *p2p.NetAddress, chainID string, protocol p2p.ProtocolVersion, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	c, err := net.DialTimeout("tcp", addr.DialString(), timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	privKey := ed25519.GenPrivKey()
+	secretConn, err := conn.MakeSecretConnection(c, privKey)
+	if err != nil {
+		return 0, err
+	}
+	if remoteID := p2p.PubKeyToID(secretConn.RemotePubKey()); remoteID != addr.ID {
+		return 0, fmt.Errorf("peer presented node id %s, expected %s", remoteID, addr.ID)
+	}
+
+	peerInfo, err := exchangeNodeInfo(secretConn, diagnosticNodeInfo(p2p.PubKeyToID(privKey.PubKey()), chainID, protocol))
+	if err != nil {
+		return 0, err
+	}
+	if peerInfo.Network != chainID {
+		return 0, fmt.Errorf("peer is on %q, expected %q", peerInfo.Network, chainID)
+	}
+	return time.Since(start), nil
+}
+
+// crawler probes the addresses in the book, least recently probed first.
+// Addresses that answer are marked good; addresses that fail maxFailures
+// probes in a row are evicted once the book has warmed up.
+type crawler struct {
+	book        *seedBook
+	chainID     string
+	protocol    p2p.ProtocolVersion
+	interval    time.Duration
+	batchSize   int
+	maxFailures int
+	logger      log.Logger
+}
+
+func newCrawler(book *seedBook, chainID string, protocol p2p.ProtocolVersion, interval time.Duration, batchSize, maxFailures int, logger log.Logger) (*crawler, error) {
+	if interval <= 0 || batchSize <= 0 || maxFailures <= 0 {
+		return nil, errors.New("crawler_interval, crawler_batch_size and crawler_max_failures must be positive")
+	}
+	return &crawler{
+		book:        book,
+		chainID:     chainID,
+		protocol:    protocol,
+		interval:    interval,
+		batchSize:   batchSize,
+		maxFailures: maxFailures,
+		logger:      logger,
+	}, nil
+}
+
+// Run crawls every interval until quit is closed
+func (c *crawler) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.crawl()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// crawl probes the batchSize least recently probed addresses in parallel
+func (c *crawler) crawl() {
+	candidates := c.book.candidates()
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].LastProbe.Before(candidates[j].LastProbe)
+	})
+	if len(candidates) > c.batchSize {
+		candidates = candidates[:c.batchSize]
+	}
+
+	var wg sync.WaitGroup
+	for _, ka := range candidates {
+		wg.Add(1)
+		go func(addr *p2p.NetAddress) {
+			defer wg.Done()
+			latency, err := ProbePeer(addr, c.chainID, c.protocol, crawlerProbeTimeout)
+			c.record(addr, latency, err)
+		}(ka.Addr)
+	}
+	wg.Wait()
+}
+
+// record updates the book with the outcome of a probe
+func (c *crawler) record(addr *p2p.NetAddress, latency time.Duration, err error) {
+	if err == nil {
+		c.book.MarkGood(addr.ID)
+	} else {
+		c.book.markAttempt(addr)
+		c.book.metrics.crawlFailed()
+	}
+
+	c.book.mtx.Lock()
+	ka := c.book.knownAddress(addr)
+	ka.LastProbe = time.Now()
+	if err == nil {
+		ka.Latency = latency
+		ka.ProbeFailures = 0
+	} else {
+		ka.ProbeFailures++
+	}
+	failures := ka.ProbeFailures
+	c.book.mtx.Unlock()
+
+	if err == nil {
+		c.logger.Debug("probe succeeded", "addr", addr, "latency", latency)
+		return
+	}
+	c.logger.Debug("probe failed", "addr", addr, "failures", failures, "err", err)
+	if failures >= c.maxFailures {
+		if c.book.WarmingUp() {
+			c.logger.Debug("keeping unreachable address during warm-up", "addr", addr, "failures", failures)
+			return
+		}
+		c.logger.Info("evicting unreachable address", "addr", addr, "failures", failures)
+		c.book.RemoveAddress(addr)
+	}
+}
+
+// preferVerified narrows candidates to the addresses a probe reached within
+// window, as long as there are at least n of them
+func preferVerified(candidates []KnownAddress, n int, window time.Duration) []KnownAddress {
+	var verified []KnownAddress
+	for _, ka := range candidates {
+		if ka.ProbeFailures == 0 && !ka.LastProbe.IsZero() && time.Since(ka.LastProbe) <= window {
+			verified = append(verified, ka)
+		}
+	}
+	if len(verified) < n {
+		return candidates
+	}
+	return verified
+}
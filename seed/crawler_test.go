@@ -0,0 +1,35 @@
+package seed
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+func TestCrawlerFailuresAreNotDialFailures(t *testing.T) {
+	book := newTestSeedBook(t, 1, false)
+	book.metrics = newSeedMetrics("test")
+	c, err := newCrawler(book, "test", p2p.ProtocolVersion{}, crawlerProbeTimeout, 1, 3, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := p2p.NewNetAddressString("0123456789abcdef0123456789abcdef01234567@1.2.3.4:26656")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.record(addr, 0, errors.New("connection refused"))
+
+	if n := testutil.ToFloat64(book.metrics.crawlFailures); n != 1 {
+		t.Errorf("counted %v crawl failures, want 1", n)
+	}
+	if n := testutil.ToFloat64(book.metrics.dialFailures); n != 0 {
+		t.Errorf("a failed probe counted %v dial failures", n)
+	}
+	if ka := book.known[addr.ID]; ka == nil || ka.Attempts != 1 || ka.ProbeFailures != 1 {
+		t.Errorf("failed probe not recorded in the book: %+v", ka)
+	}
+}
@@ -0,0 +1,239 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/protoio"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/conn"
+	"github.com/tendermint/tendermint/p2p/pex"
+	tmp2p "github.com/tendermint/tendermint/proto/tendermint/p2p"
+)
+
+// DiagnosticCheck is the outcome of a single diagnose-peer step
+type DiagnosticCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// PeerDiagnosis collects everything diagnose-peer learned about a peer
+type PeerDiagnosis struct {
+	Address  string
+	Checks   []DiagnosticCheck
+	NodeInfo *p2p.DefaultNodeInfo
+}
+
+// OK reports whether every check passed
+func (d *PeerDiagnosis) OK() bool {
+	for _, c := range d.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *PeerDiagnosis) pass(name, format string, args ...interface{}) {
+	d.Checks = append(d.Checks, DiagnosticCheck{Name: name, OK: true, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (d *PeerDiagnosis) fail(name, format string, args ...interface{}) {
+	d.Checks = append(d.Checks, DiagnosticCheck{Name: name, OK: false, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Print writes a human readable summary of the diagnosis to w
+func (d *PeerDiagnosis) Print(w io.Writer) {
+	fmt.Fprintf(w, "peer: %s\n", d.Address)
+	if d.NodeInfo != nil {
+		fmt.Fprintf(w, "  node id:   %s\n", d.NodeInfo.DefaultNodeID)
+		fmt.Fprintf(w, "  network:   %s\n", d.NodeInfo.Network)
+		fmt.Fprintf(w, "  version:   %s (p2p %d, block %d)\n",
+			d.NodeInfo.Version, d.NodeInfo.ProtocolVersion.P2P, d.NodeInfo.ProtocolVersion.Block)
+		fmt.Fprintf(w, "  moniker:   %s\n", d.NodeInfo.Moniker)
+		fmt.Fprintf(w, "  listen:    %s\n", d.NodeInfo.ListenAddr)
+		fmt.Fprintf(w, "  channels:  %X\n", d.NodeInfo.Channels)
+	}
+	for _, c := range d.Checks {
+		status := "ok"
+		if !c.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "[%4s] %-10s %s\n", status, c.Name, c.Detail)
+	}
+	if d.OK() {
+		fmt.Fprintln(w, "result: all checks passed")
+	} else {
+		fmt.Fprintln(w, "result: some checks failed")
+	}
+}
+
+// DiagnosePeer connects to addr with a throwaway identity and walks through
+// every step a seed goes through with a peer, stopping at the first step that
+// cannot be completed.  protocol is the version we claim to speak.
+func DiagnosePeer(addr *p2p.NetAddress, chainID string, protocol p2p.ProtocolVersion, timeout time.Duration) *PeerDiagnosis {
+	d := &PeerDiagnosis{Address: addr.String()}
+
+	start := time.Now()
+	c, err := net.DialTimeout("tcp", addr.DialString(), timeout)
+	if err != nil {
+		d.fail("tcp", "%v", err)
+		return d
+	}
+	defer c.Close()
+	d.pass("tcp", "connected in %s", time.Since(start))
+
+	if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+		d.fail("handshake", "%v", err)
+		return d
+	}
+
+	start = time.Now()
+	privKey := ed25519.GenPrivKey()
+	secretConn, err := conn.MakeSecretConnection(c, privKey)
+	if err != nil {
+		d.fail("handshake", "%v", err)
+		return d
+	}
+	remoteID := p2p.PubKeyToID(secretConn.RemotePubKey())
+	if remoteID != addr.ID {
+		d.fail("handshake", "peer presented node id %s, expected %s", remoteID, addr.ID)
+		return d
+	}
+	d.pass("handshake", "secret connection established in %s", time.Since(start))
+
+	ourInfo := diagnosticNodeInfo(p2p.PubKeyToID(privKey.PubKey()), chainID, protocol)
+	peerInfo, err := exchangeNodeInfo(secretConn, ourInfo)
+	if err != nil {
+		d.fail("nodeinfo", "%v", err)
+		return d
+	}
+	d.NodeInfo = &peerInfo
+	if err := peerInfo.Validate(); err != nil {
+		d.fail("nodeinfo", "invalid node info: %v", err)
+	} else {
+		d.pass("nodeinfo", "received")
+	}
+
+	if peerInfo.Network != chainID {
+		d.fail("chain", "peer is on %q, expected %q", peerInfo.Network, chainID)
+	} else {
+		d.pass("chain", "%s", peerInfo.Network)
+	}
+
+	if peerInfo.ProtocolVersion.P2P != protocol.P2P || peerInfo.ProtocolVersion.Block != protocol.Block {
+		d.fail("protocol", "peer speaks p2p %d block %d, we speak p2p %d block %d",
+			peerInfo.ProtocolVersion.P2P, peerInfo.ProtocolVersion.Block, protocol.P2P, protocol.Block)
+	} else {
+		d.pass("protocol", "p2p %d block %d", peerInfo.ProtocolVersion.P2P, peerInfo.ProtocolVersion.Block)
+	}
+
+	if err := c.SetDeadline(time.Time{}); err != nil {
+		d.fail("pex", "%v", err)
+		return d
+	}
+
+	n, err := requestPEX(secretConn, timeout)
+	if err != nil {
+		d.fail("pex", "%v", err)
+	} else {
+		d.pass("pex", "peer returned %d addresses", n)
+	}
+
+	return d
+}
+
+// diagnosticNodeInfo describes the throwaway identity used by diagnose-peer
+func diagnosticNodeInfo(id p2p.ID, chainID string, protocol p2p.ProtocolVersion) p2p.DefaultNodeInfo {
+	return p2p.DefaultNodeInfo{
+		ProtocolVersion: protocol,
+		DefaultNodeID:   id,
+		ListenAddr:      "tcp://0.0.0.0:0",
+		Network:         chainID,
+		Version:         Version,
+		Channels:        []byte{pex.PexChannel},
+		Moniker:         "tinyseed-diagnose",
+	}
+}
+
+// exchangeNodeInfo swaps node info with the peer the way the tendermint
+// transport does after the secret connection is up
+func exchangeNodeInfo(c net.Conn, ourInfo p2p.DefaultNodeInfo) (p2p.DefaultNodeInfo, error) {
+	errc := make(chan error, 2)
+	var pbPeerInfo tmp2p.DefaultNodeInfo
+
+	go func() {
+		_, err := protoio.NewDelimitedWriter(c).WriteMsg(ourInfo.ToProto())
+		errc <- err
+	}()
+	go func() {
+		_, err := protoio.NewDelimitedReader(c, p2p.MaxNodeInfoSize()).ReadMsg(&pbPeerInfo)
+		errc <- err
+	}()
+
+	for i := 0; i < cap(errc); i++ {
+		if err := <-errc; err != nil {
+			return p2p.DefaultNodeInfo{}, err
+		}
+	}
+	return p2p.DefaultNodeInfoFromToProto(&pbPeerInfo)
+}
+
+// requestPEX asks the peer for addresses and returns how many it sent back
+func requestPEX(c net.Conn, timeout time.Duration) (int, error) {
+	type result struct {
+		n   int
+		err error
+	}
+	results := make(chan result, 1)
+	report := func(r result) {
+		select {
+		case results <- r:
+		default:
+		}
+	}
+
+	onReceive := func(chID byte, msgBytes []byte) {
+		msg := &tmp2p.Message{}
+		if err := msg.Unmarshal(msgBytes); err != nil {
+			report(result{err: err})
+			return
+		}
+		if addrs, ok := msg.Sum.(*tmp2p.Message_PexAddrs); ok {
+			report(result{n: len(addrs.PexAddrs.Addrs)})
+		}
+	}
+	onError := func(r interface{}) {
+		report(result{err: fmt.Errorf("connection closed: %v", r)})
+	}
+
+	chDescs := []*conn.ChannelDescriptor{{ID: pex.PexChannel, Priority: 1, SendQueueCapacity: 10}}
+	mconn := conn.NewMConnection(c, chDescs, onReceive, onError)
+	mconn.SetLogger(log.NewNopLogger())
+	if err := mconn.Start(); err != nil {
+		return 0, err
+	}
+	defer mconn.Stop()
+
+	req := tmp2p.Message{Sum: &tmp2p.Message_PexRequest{PexRequest: &tmp2p.PexRequest{}}}
+	bz, err := req.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	if !mconn.Send(pex.PexChannel, bz) {
+		return 0, errors.New("failed to send PEX request")
+	}
+
+	select {
+	case r := <-results:
+		return r.n, r.err
+	case <-time.After(timeout):
+		return 0, fmt.Errorf("no PEX response within %s", timeout)
+	}
+}
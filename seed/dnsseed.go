@@ -0,0 +1,223 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNS seed responses are capped so that they fit in a plain 512 byte UDP
+// message: A and AAAA answers take up to 28 bytes each, TXT answers about
+// 90 for an id@host:port
+const (
+	dnsSeedMaxMessage = 512
+	dnsSeedMaxAddrs   = 12
+	dnsSeedMaxTXT     = 4
+)
+
+// DNSSeed answers DNS queries for a zone with addresses from the address
+// books, like the DNS seeds of bitcoin.  A and AAAA records carry the
+// verified peers listening on the default port, TXT records id@host:port
+// strings of verified peers on any port.
+type DNSSeed struct {
+	zone   string
+	ttl    uint32
+	port   uint16
+	window time.Duration
+	books  map[string]*seedBook
+	conn   net.PacketConn
+	logger log.Logger
+}
+
+// StartDNSSeed answers queries for zone on the UDP address addr.  With a
+// single book the zone itself is answered; every book is also answered at
+// <chain_id>.<zone>.  Only peers reached within window are returned.
+func StartDNSSeed(addr, zone string, ttl time.Duration, port int, window time.Duration, books map[string]*seedBook, logger log.Logger) (*DNSSeed, error) {
+	if zone == "" {
+		return nil, errors.New("dns_seed_zone is required with dns_seed_listen_addr")
+	}
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("dns_seed_port must be a port number, got %d", port)
+	}
+	if ttl < 0 || window <= 0 {
+		return nil, errors.New("dns_seed_ttl must not be negative and dns_seed_verified_window must be positive")
+	}
+	if _, err := dnsmessage.NewName(dnsFQDN(zone)); err != nil {
+		return nil, fmt.Errorf("dns_seed_zone: %w", err)
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	d := &DNSSeed{
+		zone:   dnsFQDN(zone),
+		ttl:    uint32(ttl / time.Second),
+		port:   uint16(port),
+		window: window,
+		books:  books,
+		conn:   conn,
+		logger: logger,
+	}
+	go d.serve()
+	return d, nil
+}
+
+// Close stops answering queries
+func (d *DNSSeed) Close() error {
+	return d.conn.Close()
+}
+
+// dnsFQDN returns name in lower case with the trailing dot
+func dnsFQDN(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+func (d *DNSSeed) serve() {
+	buf := make([]byte, dnsSeedMaxMessage)
+	for {
+		n, from, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			d.logger.Error("failed to read DNS query", "err", err)
+			continue
+		}
+		resp, err := d.answer(buf[:n])
+		if err != nil {
+			d.logger.Debug("ignoring DNS query", "from", from, "err", err)
+			continue
+		}
+		if _, err := d.conn.WriteTo(resp, from); err != nil {
+			d.logger.Debug("failed to answer DNS query", "from", from, "err", err)
+		}
+	}
+}
+
+// answer builds the response to the query in req
+func (d *DNSSeed) answer(req []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(req)
+	if err != nil {
+		return nil, err
+	}
+	if h.Response {
+		return nil, errors.New("not a query")
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	book, rcode := d.lookup(q.Name.String())
+	if h.OpCode != 0 {
+		book, rcode = nil, dnsmessage.RCodeNotImplemented
+	}
+
+	b := dnsmessage.NewBuilder(make([]byte, 0, dnsSeedMaxMessage), dnsmessage.Header{
+		ID:               h.ID,
+		Response:         true,
+		Authoritative:    rcode != dnsmessage.RCodeRefused,
+		RecursionDesired: h.RecursionDesired,
+		RCode:            rcode,
+	})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if book != nil && q.Class == dnsmessage.ClassINET {
+		if err := d.answerType(&b, q, book); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// lookup returns the book for name, or the rcode to answer with when there
+// is none
+func (d *DNSSeed) lookup(name string) (*seedBook, dnsmessage.RCode) {
+	name = strings.ToLower(name)
+	if name == d.zone {
+		if len(d.books) == 1 {
+			for _, book := range d.books {
+				return book, dnsmessage.RCodeSuccess
+			}
+		}
+		return nil, dnsmessage.RCodeSuccess
+	}
+	if !strings.HasSuffix(name, "."+d.zone) {
+		return nil, dnsmessage.RCodeRefused
+	}
+	if book, ok := d.books[strings.TrimSuffix(name, "."+d.zone)]; ok {
+		return book, dnsmessage.RCodeSuccess
+	}
+	return nil, dnsmessage.RCodeNameError
+}
+
+// answerType adds the records of q's type.  Other types get an empty
+// answer.
+func (d *DNSSeed) answerType(b *dnsmessage.Builder, q dnsmessage.Question, book *seedBook) error {
+	hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: d.ttl}
+	switch q.Type {
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		for _, ka := range d.verified(book, dnsSeedMaxAddrs, func(ka KnownAddress) bool {
+			isV4 := ka.Addr.IP.To4() != nil
+			return ka.Addr.Port == d.port && isV4 == (q.Type == dnsmessage.TypeA)
+		}) {
+			var err error
+			if q.Type == dnsmessage.TypeA {
+				var a dnsmessage.AResource
+				copy(a.A[:], ka.Addr.IP.To4())
+				err = b.AResource(hdr, a)
+			} else {
+				var aaaa dnsmessage.AAAAResource
+				copy(aaaa.AAAA[:], ka.Addr.IP.To16())
+				err = b.AAAAResource(hdr, aaaa)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+	case dnsmessage.TypeTXT:
+		for _, ka := range d.verified(book, dnsSeedMaxTXT, func(KnownAddress) bool { return true }) {
+			if err := b.TXTResource(hdr, dnsmessage.TXTResource{TXT: []string{ka.Addr.String()}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verified picks up to n random addresses from book that match, may be
+// relayed and were reached by the seed or the crawler within the window
+func (d *DNSSeed) verified(book *seedBook, n int, match func(KnownAddress) bool) []KnownAddress {
+	var picked []KnownAddress
+	for _, ka := range book.candidates() {
+		reached := time.Since(ka.LastSuccess) <= d.window ||
+			(ka.ProbeFailures == 0 && !ka.LastProbe.IsZero() && time.Since(ka.LastProbe) <= d.window)
+		if reached && book.relay(ka.Addr) && match(ka) {
+			picked = append(picked, ka)
+		}
+	}
+	rand.Shuffle(len(picked), func(i, j int) {
+		picked[i], picked[j] = picked[j], picked[i]
+	})
+	if len(picked) > n {
+		picked = picked[:n]
+	}
+	return picked
+}
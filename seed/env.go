@@ -0,0 +1,58 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts the environment variable of every setting
+const EnvPrefix = "TINYSEED_"
+
+// EnvVar returns the environment variable overriding the setting with the
+// given TOML key, e.g. TINYSEED_ADDR_BOOK_STRICT for addr_book_strict
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// configKeys returns the TOML key of every Config field, by field name
+func configKeys() map[string]string {
+	keys := make(map[string]string)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+		if key != "" && key != "-" {
+			keys[t.Field(i).Name] = key
+		}
+	}
+	return keys
+}
+
+// ApplyEnv sets every setting whose environment variable is set on top of
+// cfg.  Strings and durations are taken as they are, e.g.
+// TINYSEED_WARM_UP_PERIOD=10m; everything else is read as a TOML value, e.g.
+// TINYSEED_SEED_PUBLIC_KEYS='{ "id@host:port" = "id" }'.
+func ApplyEnv(cfg Config) (Config, error) {
+	t := reflect.TypeOf(cfg)
+	for name, key := range configKeys() {
+		value, ok := os.LookupEnv(EnvVar(key))
+		if !ok {
+			continue
+		}
+
+		field, _ := t.FieldByName(name)
+		if field.Type.Kind() == reflect.String || field.Type == reflect.TypeOf(time.Duration(0)) {
+			value = strconv.Quote(value)
+		}
+
+		var err error
+		cfg, err = ParseConfig([]byte(key+" = "+value+"\n"), cfg)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", EnvVar(key), err)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,207 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+
+	"github.com/oschwald/maxminddb-golang"
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// Geographic preferences accepted by Config.AddrBookGeographicPreference
+const (
+	GeoPreferenceNone    = "none"
+	GeoPreferenceNear    = "near"
+	GeoPreferenceDiverse = "diverse"
+
+	// GeoPreferenceRequester favours peers close to whoever asks, falling
+	// back to GeoPreferenceDiverse for requesters the database does not know
+	GeoPreferenceRequester = "requester"
+)
+
+// earthRadiusKm is the mean radius of the earth
+const earthRadiusKm = 6371.0
+
+// GeoLocation is what a GeoIP database knows about an address
+type GeoLocation struct {
+	Country   string
+	Continent string
+	Latitude  float64
+	Longitude float64
+}
+
+// GeoIP looks addresses up in a MaxMind GeoLite2 City or Country database
+type GeoIP struct {
+	db *maxminddb.Reader
+}
+
+// OpenGeoIP opens the database at path
+func OpenGeoIP(path string) (*GeoIP, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &GeoIP{db: db}, nil
+}
+
+// Lookup returns the location of ip.  ok is false if the database has no
+// country for it.
+func (g *GeoIP) Lookup(ip net.IP) (loc GeoLocation, ok bool) {
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		Continent struct {
+			Code string `maxminddb:"code"`
+		} `maxminddb:"continent"`
+		Location struct {
+			Latitude  float64 `maxminddb:"latitude"`
+			Longitude float64 `maxminddb:"longitude"`
+		} `maxminddb:"location"`
+	}
+	if err := g.db.Lookup(ip, &record); err != nil || record.Country.ISOCode == "" {
+		return GeoLocation{}, false
+	}
+	return GeoLocation{
+		Country:   record.Country.ISOCode,
+		Continent: record.Continent.Code,
+		Latitude:  record.Location.Latitude,
+		Longitude: record.Location.Longitude,
+	}, true
+}
+
+// Close releases the database
+func (g *GeoIP) Close() error {
+	return g.db.Close()
+}
+
+// distanceKm is the great-circle distance between a and b
+func distanceKm(a, b GeoLocation) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// geoSelector orders PEX candidates by geography
+type geoSelector struct {
+	geoip      *GeoIP
+	preference string
+
+	// origin is where the seed itself is, for GeoPreferenceNear
+	origin GeoLocation
+}
+
+// newGeoSelector validates preference.  origin is the seed's own address,
+// which must be known to the database for GeoPreferenceNear.
+func newGeoSelector(preference string, geoip *GeoIP, origin *p2p.NetAddress) (*geoSelector, error) {
+	switch preference {
+	case "", GeoPreferenceNone:
+		return nil, nil
+	case GeoPreferenceNear, GeoPreferenceDiverse, GeoPreferenceRequester:
+	default:
+		return nil, fmt.Errorf("unknown geographic preference %q", preference)
+	}
+	if geoip == nil {
+		return nil, fmt.Errorf("geographic preference %q requires geoip_database to be set", preference)
+	}
+
+	s := &geoSelector{geoip: geoip, preference: preference}
+	if preference == GeoPreferenceNear {
+		loc, ok := geoip.Lookup(origin.IP)
+		if !ok {
+			return nil, fmt.Errorf("geographic preference %q: the GeoIP database does not know our address %s", preference, origin.IP)
+		}
+		s.origin = loc
+	}
+	return s, nil
+}
+
+// perRequester reports whether selections depend on who asks for them
+func (s *geoSelector) perRequester() bool {
+	return s != nil && s.preference == GeoPreferenceRequester
+}
+
+// Select picks up to n of candidates according to the preference.
+// requester is the address of the peer asking, nil if unknown.
+func (s *geoSelector) Select(candidates []KnownAddress, n int, requester net.IP) []KnownAddress {
+	if n >= len(candidates) {
+		return candidates
+	}
+	switch s.preference {
+	case GeoPreferenceNear:
+		return s.nearest(candidates, n, s.origin)
+	case GeoPreferenceRequester:
+		if requester != nil {
+			if loc, ok := s.geoip.Lookup(requester); ok {
+				return s.nearest(candidates, n, loc)
+			}
+		}
+	}
+	return s.diverse(candidates, n)
+}
+
+// nearest returns the n candidates closest to origin.  Addresses the
+// database does not know sort last.
+func (s *geoSelector) nearest(candidates []KnownAddress, n int, origin GeoLocation) []KnownAddress {
+	distances := make(map[p2p.ID]float64, len(candidates))
+	for _, ka := range candidates {
+		d := math.Inf(1)
+		if loc, ok := s.geoip.Lookup(ka.Addr.IP); ok {
+			d = distanceKm(origin, loc)
+		}
+		distances[ka.Addr.ID] = d
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distances[candidates[i].Addr.ID] < distances[candidates[j].Addr.ID]
+	})
+	return candidates[:n]
+}
+
+// diverse picks candidates in random order, preferring ones from continents
+// and then countries not yet in the selection
+func (s *geoSelector) diverse(candidates []KnownAddress, n int) []KnownAddress {
+	shuffled := make([]KnownAddress, len(candidates))
+	for i, j := range tmrand.Perm(len(candidates)) {
+		shuffled[i] = candidates[j]
+	}
+	candidates = shuffled
+
+	locs := make([]GeoLocation, len(candidates))
+	for i, ka := range candidates {
+		locs[i], _ = s.geoip.Lookup(ka.Addr.IP)
+	}
+
+	continents := make(map[string]bool)
+	countries := make(map[string]bool)
+	taken := make([]bool, len(candidates))
+	selection := make([]KnownAddress, 0, n)
+
+	// first pass: a new continent, second: a new country, third: anything
+	passes := []func(GeoLocation) bool{
+		func(loc GeoLocation) bool { return loc.Continent != "" && !continents[loc.Continent] },
+		func(loc GeoLocation) bool { return loc.Country != "" && !countries[loc.Country] },
+		func(GeoLocation) bool { return true },
+	}
+	for _, wanted := range passes {
+		for i, ka := range candidates {
+			if len(selection) == n {
+				return selection
+			}
+			if taken[i] || !wanted(locs[i]) {
+				continue
+			}
+			taken[i] = true
+			continents[locs[i].Continent] = true
+			countries[locs[i].Country] = true
+			selection = append(selection, ka)
+		}
+	}
+	return selection
+}
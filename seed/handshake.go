@@ -0,0 +1,52 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+	"golang.org/x/time/rate"
+)
+
+// handshakeLimiter throttles how many connections per second may go on to
+// the secret connection handshake.  Connections wait for a token for up to
+// the queue timeout and are closed if none becomes available.
+type handshakeLimiter struct {
+	limiter *rate.Limiter
+	timeout time.Duration
+}
+
+// newHandshakeLimiter allows perSecond handshakes per second
+func newHandshakeLimiter(perSecond float64, queueTimeout time.Duration) (*handshakeLimiter, error) {
+	if perSecond < 0 {
+		return nil, fmt.Errorf("peer_handshake_rate_limit must not be negative, got %v", perSecond)
+	}
+	if queueTimeout <= 0 {
+		return nil, fmt.Errorf("handshake_queue_timeout must be positive, got %v", queueTimeout)
+	}
+
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	return &handshakeLimiter{
+		limiter: rate.NewLimiter(rate.Limit(perSecond), burst),
+		timeout: queueTimeout,
+	}, nil
+}
+
+// FilterConn is a p2p.ConnFilterFunc.  The transport runs its filters right
+// before the handshake, so blocking here queues the handshake.  It must run
+// in a sequentialConnFilter after the filters that reject, or rejected
+// connections spend tokens too.
+func (h *handshakeLimiter) FilterConn(_ p2p.ConnSet, c net.Conn, _ []net.IP) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	if err := h.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("handshake with %s not started within %v: %w", c.RemoteAddr(), h.timeout, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package seed
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// healthRecentWindow is how recently an address must have been seen to count
+// as recent in the health score
+const healthRecentWindow = 24 * time.Hour
+
+// HealthScore combines the share of recently seen addresses, the average
+// success rate and how close the book is to targetSize into a single value
+// between 0 and 1.  Weights apply in that order and are normalised by their
+// sum.
+func HealthScore(addrs []KnownAddress, targetSize int, weights [3]float64) float64 {
+	total := weights[0] + weights[1] + weights[2]
+	if len(addrs) == 0 || total <= 0 {
+		return 0
+	}
+
+	var recent int
+	var successRate float64
+	for _, ka := range addrs {
+		if time.Since(ka.LastSeen) < healthRecentWindow {
+			recent++
+		}
+		successRate += ka.SuccessRate()
+	}
+	successRate /= float64(len(addrs))
+
+	fill := 1.0
+	if targetSize > 0 && len(addrs) < targetSize {
+		fill = float64(len(addrs)) / float64(targetSize)
+	}
+
+	score := weights[0]*float64(recent)/float64(len(addrs)) +
+		weights[1]*successRate +
+		weights[2]*fill
+	return score / total
+}
+
+// validateHealthWeights rejects weights that cannot produce a score
+func validateHealthWeights(weights [3]float64) error {
+	for _, w := range weights {
+		if w < 0 {
+			return errors.New("addr_book_health_weights must not be negative")
+		}
+	}
+	if weights[0]+weights[1]+weights[2] == 0 {
+		return errors.New("addr_book_health_weights must not all be zero")
+	}
+	return nil
+}
+
+// RegisterHealthScoreMetric exports the health score of book to reg
+func RegisterHealthScoreMetric(reg prometheus.Registerer, book *seedBook, chainID string, targetSize int, weights [3]float64) error {
+	if err := validateHealthWeights(weights); err != nil {
+		return err
+	}
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "addrbook",
+		Name:        "health_score",
+		Help:        "Combined score of address book recency, success rate and size, between 0 and 1.",
+		ConstLabels: prometheus.Labels{"chain_id": chainID},
+	}, func() float64 {
+		return HealthScore(book.candidates(), targetSize, weights)
+	}))
+	return nil
+}
@@ -0,0 +1,79 @@
+package seed
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// handshakeTimerExpiry is how long a connection may go without completing
+// its handshake before we stop tracking it
+const handshakeTimerExpiry = time.Minute
+
+// EventHooks let programs embedding the seed react to what it does.  Hooks
+// run synchronously on the seed's own goroutines and must return quickly.
+type EventHooks struct {
+	// OnHandshakeComplete is called with the peer's NodeInfo once a
+	// successful handshake has passed every peer filter and the peer has
+	// been added to the switch.  direction is "inbound" or "outbound" and
+	// latency is the time from connection to the peer being added.
+	OnHandshakeComplete func(info p2p.NodeInfo, direction string, latency time.Duration)
+}
+
+// handshakeTimer measures handshakes for OnHandshakeComplete.  The transport
+// filters a connection right before its handshake, and the switch adds the
+// peer to its reactors once every peer filter has accepted it.  Peer
+// filters run concurrently, so the hook waits for AddPeer rather than
+// running as one.
+type handshakeTimer struct {
+	p2p.BaseReactor
+
+	onComplete func(info p2p.NodeInfo, direction string, latency time.Duration)
+
+	mtx     sync.Mutex
+	started map[string]time.Time
+}
+
+func newHandshakeTimer(onComplete func(info p2p.NodeInfo, direction string, latency time.Duration)) *handshakeTimer {
+	t := &handshakeTimer{
+		onComplete: onComplete,
+		started:    make(map[string]time.Time),
+	}
+	t.BaseReactor = *p2p.NewBaseReactor("Hooks", t)
+	return t
+}
+
+// FilterConn is a p2p.ConnFilterFunc noting when the handshake starts
+func (t *handshakeTimer) FilterConn(_ p2p.ConnSet, c net.Conn, _ []net.IP) error {
+	now := time.Now()
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	// forget connections whose handshake failed
+	for addr, started := range t.started {
+		if now.Sub(started) > handshakeTimerExpiry {
+			delete(t.started, addr)
+		}
+	}
+	t.started[c.RemoteAddr().String()] = now
+	return nil
+}
+
+// AddPeer implements p2p.Reactor, running the hook
+func (t *handshakeTimer) AddPeer(peer p2p.Peer) {
+	addr := peer.SocketAddr().DialString()
+
+	t.mtx.Lock()
+	started, ok := t.started[addr]
+	delete(t.started, addr)
+	t.mtx.Unlock()
+
+	var latency time.Duration
+	if ok {
+		latency = time.Since(started)
+	}
+	t.onComplete(peer.NodeInfo(), direction(peer), latency)
+}
@@ -0,0 +1,136 @@
+package seed
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/conn"
+	"github.com/tendermint/tendermint/version"
+)
+
+// handshakeEvent is one OnHandshakeComplete call
+type handshakeEvent struct {
+	id        p2p.ID
+	direction string
+	latency   time.Duration
+}
+
+// freeAddress returns a loopback address nothing listens on
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// startHookedSwitch starts a switch listening on loopback, with filters as
+// its peer filters, whose handshakes are reported to events the way New
+// wires OnHandshakeComplete
+func startHookedSwitch(t *testing.T, events chan<- handshakeEvent, filters ...p2p.PeerFilterFunc) (*p2p.Switch, *p2p.NetAddress) {
+	nodeKey := &p2p.NodeKey{PrivKey: ed25519.GenPrivKey()}
+	addr, err := p2p.NewNetAddressString(p2p.IDAddressString(nodeKey.ID(), freeAddress(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeInfo := p2p.DefaultNodeInfo{
+		ProtocolVersion: p2p.NewProtocolVersion(version.P2PProtocol, version.BlockProtocol, 0),
+		DefaultNodeID:   nodeKey.ID(),
+		ListenAddr:      addr.DialString(),
+		Network:         "test",
+		Version:         "1.0.0",
+		Channels:        []byte{0x00},
+		Moniker:         "test",
+	}
+
+	handshakes := newHandshakeTimer(func(info p2p.NodeInfo, direction string, latency time.Duration) {
+		events <- handshakeEvent{id: info.ID(), direction: direction, latency: latency}
+	})
+
+	cfg := config.DefaultP2PConfig()
+	transport := p2p.NewMultiplexTransport(nodeInfo, *nodeKey, conn.DefaultMConnConfig())
+	p2p.MultiplexTransportConnFilters(handshakes.FilterConn)(transport)
+	if err := transport.Listen(*addr); err != nil {
+		t.Fatal(err)
+	}
+
+	sw := p2p.NewSwitch(cfg, transport, p2p.SwitchPeerFilters(filters...))
+	sw.SetLogger(log.NewNopLogger())
+	sw.SetNodeInfo(nodeInfo)
+	sw.SetNodeKey(nodeKey)
+	reactor := &channelsReactor{chDescs: []*conn.ChannelDescriptor{{ID: 0x00, Priority: 1}}}
+	reactor.BaseReactor = *p2p.NewBaseReactor("test", reactor)
+	sw.AddReactor("test", reactor)
+	sw.AddReactor("hooks", handshakes)
+	if err := sw.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sw.Stop()         //nolint:errcheck
+		transport.Close() //nolint:errcheck
+	})
+	return sw, addr
+}
+
+func TestOnHandshakeCompleteInboundAndOutbound(t *testing.T) {
+	dialerEvents := make(chan handshakeEvent, 1)
+	listenerEvents := make(chan handshakeEvent, 1)
+	dialer, dialerAddr := startHookedSwitch(t, dialerEvents)
+	_, listenerAddr := startHookedSwitch(t, listenerEvents)
+
+	if err := dialer.DialPeerWithAddress(listenerAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []struct {
+		events    <-chan handshakeEvent
+		id        p2p.ID
+		direction string
+	}{
+		{dialerEvents, listenerAddr.ID, "outbound"},
+		{listenerEvents, dialerAddr.ID, "inbound"},
+	} {
+		select {
+		case event := <-want.events:
+			if event.id != want.id || event.direction != want.direction {
+				t.Errorf("got a %s handshake with %s, want a %s one with %s", event.direction, event.id, want.direction, want.id)
+			}
+			if event.latency <= 0 {
+				t.Errorf("%s handshake was not timed", want.direction)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("no %s handshake reported", want.direction)
+		}
+	}
+}
+
+func TestOnHandshakeCompleteSkipsFilteredPeers(t *testing.T) {
+	dialerEvents := make(chan handshakeEvent, 1)
+	listenerEvents := make(chan handshakeEvent, 1)
+	dialer, _ := startHookedSwitch(t, dialerEvents)
+	_, listenerAddr := startHookedSwitch(t, listenerEvents, func(p2p.IPeerSet, p2p.Peer) error {
+		return errors.New("denied")
+	})
+
+	// the dialer adds the listener before the listener turns it away
+	if err := dialer.DialPeerWithAddress(listenerAddr); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-dialerEvents:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no outbound handshake reported")
+	}
+	select {
+	case event := <-listenerEvents:
+		t.Fatalf("reported a %s handshake with %s, which a peer filter rejected", event.direction, event.id)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
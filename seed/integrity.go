@@ -0,0 +1,179 @@
+package seed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/tempfile"
+)
+
+// errAddrBookHashMismatch is returned when an address book no longer matches
+// the hash recorded when it was saved
+var errAddrBookHashMismatch = errors.New("address book does not match its recorded sha256")
+
+// errAddrBookHashMissing is returned when a book that must have a sidecar,
+// such as a backup, has none to be verified against
+var errAddrBookHashMissing = errors.New("address book has no recorded sha256")
+
+// errAddrBookUnreadable is returned when an address book is not valid JSON
+var errAddrBookUnreadable = errors.New("address book cannot be parsed")
+
+// addrBookHashFile is the sidecar holding the hash of the book at path
+func addrBookHashFile(path string) string {
+	return path + ".sha256"
+}
+
+// WriteAddrBookHash records the sha256 of the book at path in the sidecar
+// file, in the format sha256sum understands
+func WriteAddrBookHash(path string) error {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(bz)
+	line := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), filepath.Base(path))
+	return tempfile.WriteFileAtomic(addrBookHashFile(path), []byte(line), 0644)
+}
+
+// VerifyAddrBookHash checks the book at path against its sidecar.  A book or
+// sidecar that does not exist yet passes.  tendermint only ever saves to the
+// working file, so every write to path goes through SaveAddrBook and leaves
+// the sidecar in step with it.
+func VerifyAddrBookHash(path string) error {
+	bz, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	recorded, err := os.ReadFile(addrBookHashFile(path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkAddrBookHash(bz, recorded)
+}
+
+// RequireAddrBookHash is VerifyAddrBookHash for a book that must already
+// have been hashed: an existing book without a sidecar fails with
+// errAddrBookHashMissing rather than passing
+func RequireAddrBookHash(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		if _, err := os.Stat(addrBookHashFile(path)); os.IsNotExist(err) {
+			return errAddrBookHashMissing
+		}
+	}
+	return VerifyAddrBookHash(path)
+}
+
+// checkAddrBookHash checks the contents of a book against the contents of
+// its sidecar
+func checkAddrBookHash(bz, recorded []byte) error {
+	fields := bytes.Fields(recorded)
+	if len(fields) == 0 {
+		return errAddrBookHashMismatch
+	}
+
+	sum := sha256.Sum256(bz)
+	if string(fields[0]) != hex.EncodeToString(sum[:]) {
+		return errAddrBookHashMismatch
+	}
+	return nil
+}
+
+// readAddrBookBackup returns the contents of the backup at path once they
+// have been checked against its sidecar and parsed.  Every backup is written
+// with a sidecar, so a missing one fails, and the bytes checked are the bytes
+// returned.
+func readAddrBookBackup(path string) ([]byte, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := os.ReadFile(addrBookHashFile(path))
+	if os.IsNotExist(err) {
+		return nil, errAddrBookHashMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAddrBookHash(bz, recorded); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bz, &AddrBook{}); err != nil {
+		return nil, fmt.Errorf("%w: %v", errAddrBookUnreadable, err)
+	}
+	return bz, nil
+}
+
+// checkAddrBookFile verifies the book at path against its sidecar and makes
+// sure it parses.  Both failures wrap errAddrBookHashMismatch or
+// errAddrBookUnreadable.
+func checkAddrBookFile(path string) error {
+	if err := VerifyAddrBookHash(path); err != nil {
+		return err
+	}
+	if _, _, err := LoadAddrBook(path); err != nil {
+		return fmt.Errorf("%w: %v", errAddrBookUnreadable, err)
+	}
+	return nil
+}
+
+// addrBookCorrupted reports whether err means the book itself is damaged,
+// as opposed to being unreadable for some other reason
+func addrBookCorrupted(err error) bool {
+	return errors.Is(err, errAddrBookHashMismatch) || errors.Is(err, errAddrBookUnreadable)
+}
+
+// CheckAddrBookIntegrity verifies the book at path before it is loaded.  A
+// corrupted book is moved aside and replaced with its newest backup that
+// matches its own sidecar and parses, or else dropped so the seed starts
+// with an empty book.
+func CheckAddrBookIntegrity(path string, logger log.Logger) error {
+	err := checkAddrBookFile(path)
+	if err == nil || !addrBookCorrupted(err) {
+		return err
+	}
+	logger.Error("address book failed its integrity check", "file", path, "err", err)
+
+	corruptPath := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+	if err := os.Rename(path, corruptPath); err != nil {
+		return err
+	}
+
+	backups, err := addrBookBackups(path)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		bz, err := readAddrBookBackup(backup)
+		if err != nil {
+			logger.Error("address book backup failed its integrity check too", "backup", backup, "err", err)
+			continue
+		}
+		if err := tempfile.WriteFileAtomic(path, bz, 0644); err != nil {
+			return err
+		}
+		logger.Error("restored address book from backup", "backup", backup, "corrupt-copy", corruptPath)
+		return WriteAddrBookHash(path)
+	}
+
+	logger.Error("!!! STARTING WITH AN EMPTY ADDRESS BOOK !!! the corrupted book was kept for inspection",
+		"corrupt-copy", corruptPath)
+	if err := os.Remove(addrBookHashFile(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package seed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestVerifyAddrBookHashCatchesLaterWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addrbook.json")
+	if err := SaveAddrBook(path, &AddrBook{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAddrBookHash(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// a write that bypasses SaveAddrBook is caught however recent it is
+	if err := os.WriteFile(path, []byte(`{"key":"","addrs":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAddrBookHash(path); !errors.Is(err, errAddrBookHashMismatch) {
+		t.Fatalf("expected a hash mismatch, got %v", err)
+	}
+}
+
+func TestCheckAddrBookIntegrityRestoresOnlyVerifiedBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addrbook.json")
+	addrs := testAddrs(3)
+	writeBackup := func(when string, addr int) string {
+		backup := path + ".bak." + when
+		book := &AddrBook{Addrs: []*AddrBookEntry{{Addr: addrs[addr], Src: addrs[addr]}}}
+		if err := SaveAddrBook(backup, book); err != nil {
+			t.Fatal(err)
+		}
+		return backup
+	}
+	writeBackup("20260101T000000.000Z", 0)
+	unsigned := writeBackup("20260102T000000.000Z", 1)
+	if err := os.Remove(addrBookHashFile(unsigned)); err != nil {
+		t.Fatal(err)
+	}
+	tampered := writeBackup("20260103T000000.000Z", 2)
+	if err := os.WriteFile(tampered, []byte(`{"key":"","addrs":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveAddrBook(path, &AddrBook{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckAddrBookIntegrity(path, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := LoadAddrBookEntries([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Addr.ID != addrs[0].ID {
+		t.Fatalf("restored %v, want the only backup that matches its sidecar", entries)
+	}
+	if err := VerifyAddrBookHash(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckAddrBookIntegrityStartsEmptyWithoutSidecarOrBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addrbook.json")
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckAddrBookIntegrity(path, log.NewNopLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the garbled book was not moved aside: %v", err)
+	}
+}
+
+func TestRequireAddrBookHashFailsWithoutSidecar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addrbook.json")
+	if err := os.WriteFile(path, []byte(`{"key":"","addrs":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAddrBookHash(path); err != nil {
+		t.Fatalf("startup check refused a book that was never hashed: %v", err)
+	}
+	if err := RequireAddrBookHash(path); !errors.Is(err, errAddrBookHashMissing) {
+		t.Fatalf("expected a missing hash, got %v", err)
+	}
+
+	if err := WriteAddrBookHash(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := RequireAddrBookHash(path); err != nil {
+		t.Fatal(err)
+	}
+}
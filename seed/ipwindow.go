@@ -0,0 +1,62 @@
+package seed
+
+import "time"
+
+// ipWindow counts events per IP over a sliding window.  IPs without events
+// in the window are forgotten at most once per window, so the map only
+// holds recently active IPs.  The caller provides locking.
+type ipWindow struct {
+	window time.Duration
+	events map[string][]time.Time
+	swept  time.Time
+}
+
+func newIPWindow(window time.Duration) *ipWindow {
+	return &ipWindow{
+		window: window,
+		events: make(map[string][]time.Time),
+	}
+}
+
+// Count returns how many events ip had within the window before now
+func (w *ipWindow) Count(ip string, now time.Time) int {
+	events := w.events[ip][:0]
+	for _, at := range w.events[ip] {
+		if now.Sub(at) < w.window {
+			events = append(events, at)
+		}
+	}
+	if len(events) == 0 {
+		delete(w.events, ip)
+	} else {
+		w.events[ip] = events
+	}
+	w.forgetIdle(now)
+	return len(events)
+}
+
+// Add records an event for ip at now and returns the count including it
+func (w *ipWindow) Add(ip string, now time.Time) int {
+	count := w.Count(ip, now)
+	w.events[ip] = append(w.events[ip], now)
+	return count + 1
+}
+
+// Forget drops every event of ip
+func (w *ipWindow) Forget(ip string) {
+	delete(w.events, ip)
+}
+
+// forgetIdle drops IPs without events in the window, at most once per
+// window
+func (w *ipWindow) forgetIdle(now time.Time) {
+	if now.Sub(w.swept) < w.window {
+		return
+	}
+	w.swept = now
+	for ip, events := range w.events {
+		if len(events) == 0 || now.Sub(events[len(events)-1]) >= w.window {
+			delete(w.events, ip)
+		}
+	}
+}
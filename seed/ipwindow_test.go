@@ -0,0 +1,27 @@
+package seed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIPWindowForgetsIdleIPs(t *testing.T) {
+	w := newIPWindow(time.Minute)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if n := w.Add("10.0.0.1", start.Add(time.Duration(i)*time.Second)); n != i+1 {
+			t.Fatalf("count %d after %d events", n, i+1)
+		}
+	}
+	w.Add("10.0.0.2", start)
+	if n := w.Count("10.0.0.1", start.Add(61*time.Second)); n != 1 {
+		t.Fatalf("expected one event left in the window, got %d", n)
+	}
+
+	// 10.0.0.2 never shows up again, but the next sweep drops it
+	w.Count("10.0.0.1", start.Add(3*time.Minute))
+	if len(w.events) != 0 {
+		t.Fatalf("idle IPs were kept: %v", w.events)
+	}
+}
@@ -0,0 +1,209 @@
+package seed
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"plugin"
+	"strings"
+	"time"
+
+	tmed25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// NodeKeyEnv names the variable EnvKeyManager reads the node key from
+const NodeKeyEnv = "TINYSEED_NODE_KEY"
+
+// vaultTimeout bounds requests to Vault
+const vaultTimeout = 10 * time.Second
+
+// KeyManager loads the node key the seed identifies itself with
+type KeyManager interface {
+	LoadKey(cfg Config) (*p2p.NodeKey, error)
+}
+
+// keyManagers holds the built-in key managers by name
+var keyManagers = map[string]KeyManager{
+	"file":   FileKeyManager{},
+	"env":    EnvKeyManager{},
+	"secret": SecretFileKeyManager{},
+	"vault":  VaultKeyManager{},
+}
+
+// RegisterKeyManager makes km available as KeyManagerPlugin = name
+func RegisterKeyManager(name string, km KeyManager) {
+	keyManagers[name] = km
+}
+
+// LoadKeyManager returns the key manager registered under name, or opens
+// name as a Go plugin if no built-in matches.  A plugin exports a KeyManager
+// under the symbol name "KeyManager", and must be built against the same
+// version of this package as the seed.
+func LoadKeyManager(name string) (KeyManager, error) {
+	if name == "" {
+		name = "file"
+	}
+	if km, ok := keyManagers[name]; ok {
+		return km, nil
+	}
+
+	p, err := plugin.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("key manager %q is neither built in nor a loadable plugin: %w", name, err)
+	}
+	sym, err := p.Lookup("KeyManager")
+	if err != nil {
+		return nil, err
+	}
+
+	// a plugin may export the value itself or a variable holding it
+	if km, ok := sym.(*KeyManager); ok {
+		sym = *km
+	}
+	km, ok := sym.(KeyManager)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: KeyManager does not implement seed.KeyManager", name)
+	}
+	return km, nil
+}
+
+// FileKeyManager loads the node key from NodeKeyFile, generating one if the
+// file does not exist yet.  Logger receives NodeKeyRecoveryMode warnings.
+type FileKeyManager struct {
+	Logger log.Logger
+}
+
+// LoadKey implements KeyManager
+func (km FileKeyManager) LoadKey(cfg Config) (*p2p.NodeKey, error) {
+	if !cfg.NodeKeyRecoveryMode {
+		return p2p.LoadOrGenNodeKey(cfg.NodeKeyFile)
+	}
+
+	logger := km.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return LoadOrRecoverNodeKey(cfg.NodeKeyFile, logger)
+}
+
+// EnvKeyManager reads a base64 encoded ed25519 private key or seed from
+// TINYSEED_NODE_KEY
+type EnvKeyManager struct{}
+
+// LoadKey implements KeyManager
+func (EnvKeyManager) LoadKey(cfg Config) (*p2p.NodeKey, error) {
+	encoded, ok := os.LookupEnv(NodeKeyEnv)
+	if !ok {
+		return nil, fmt.Errorf("%s is not set", NodeKeyEnv)
+	}
+	return decodeNodeKey(encoded)
+}
+
+// SecretFileKeyManager reads the node key from NodeKeySecretFile, such as a
+// mounted kubernetes secret.  The file holds a base64 encoded ed25519
+// private key or seed, or a node_key.json, and is never written to.
+type SecretFileKeyManager struct{}
+
+// LoadKey implements KeyManager
+func (SecretFileKeyManager) LoadKey(cfg Config) (*p2p.NodeKey, error) {
+	if cfg.NodeKeySecretFile == "" {
+		return nil, errors.New("node_key_secret_file is not set")
+	}
+	bz, err := os.ReadFile(cfg.NodeKeySecretFile)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(bz)), "{") {
+		return p2p.LoadNodeKey(cfg.NodeKeySecretFile)
+	}
+	nodeKey, err := decodeNodeKey(string(bz))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.NodeKeySecretFile, err)
+	}
+	return nodeKey, nil
+}
+
+// VaultKeyManager reads a base64 encoded ed25519 private key or seed from the
+// "priv_key" field of a Vault KV secret at VaultKeyPath, using VAULT_ADDR
+// and VAULT_TOKEN like the vault CLI does
+type VaultKeyManager struct{}
+
+// LoadKey implements KeyManager
+func (VaultKeyManager) LoadKey(cfg Config) (*p2p.NodeKey, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	token := os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	if cfg.VaultKeyPath == "" {
+		return nil, errors.New("vault_key_path is not set")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(cfg.VaultKeyPath, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	client := &http.Client{Timeout: vaultTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s for %s", resp.Status, cfg.VaultKeyPath)
+	}
+
+	// KV v2 nests the secret one level deeper than KV v1
+	var secret struct {
+		Data struct {
+			PrivKey string `json:"priv_key"`
+			Data    struct {
+				PrivKey string `json:"priv_key"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, err
+	}
+
+	encoded := secret.Data.Data.PrivKey
+	if encoded == "" {
+		encoded = secret.Data.PrivKey
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("vault secret %s has no priv_key field", cfg.VaultKeyPath)
+	}
+	return decodeNodeKey(encoded)
+}
+
+// decodeNodeKey parses a base64 encoded ed25519 key: either a 64 byte
+// private key, whose public half must match the one its seed derives, or a
+// 32 byte seed, which always derives the same key and so the same node ID
+func decodeNodeKey(encoded string) (*p2p.NodeKey, error) {
+	bz, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("node key is not valid base64: %w", err)
+	}
+	switch len(bz) {
+	case ed25519.SeedSize:
+		return &p2p.NodeKey{PrivKey: tmed25519.PrivKey(ed25519.NewKeyFromSeed(bz))}, nil
+	case ed25519.PrivateKeySize:
+		derived := ed25519.NewKeyFromSeed(bz[:ed25519.SeedSize])
+		if !bytes.Equal(bz[ed25519.SeedSize:], derived[ed25519.SeedSize:]) {
+			return nil, errors.New("node key is corrupted: its public half does not match its seed")
+		}
+		return &p2p.NodeKey{PrivKey: tmed25519.PrivKey(bz)}, nil
+	default:
+		return nil, fmt.Errorf("node key is %d bytes, expected a %d byte private key or a %d byte seed",
+			len(bz), ed25519.PrivateKeySize, ed25519.SeedSize)
+	}
+}
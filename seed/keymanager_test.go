@@ -0,0 +1,34 @@
+package seed
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+func TestDecodeNodeKeyChecksThePublicHalf(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+
+	fromKey, err := decodeNodeKey(base64.StdEncoding.EncodeToString(priv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSeed, err := decodeNodeKey(base64.StdEncoding.EncodeToString(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromKey.ID() != fromSeed.ID() {
+		t.Fatalf("seed gave %s, the key it derives gave %s", fromSeed.ID(), fromKey.ID())
+	}
+
+	// a key whose public half belongs to another seed
+	other := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	mismatched := append(append([]byte{}, priv[:ed25519.SeedSize]...), other[ed25519.SeedSize:]...)
+	if _, err := decodeNodeKey(base64.StdEncoding.EncodeToString(mismatched)); err == nil {
+		t.Fatal("a key whose public half does not match its seed was accepted")
+	}
+}
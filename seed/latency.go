@@ -0,0 +1,216 @@
+package seed
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+	tmp2p "github.com/tendermint/tendermint/proto/tendermint/p2p"
+)
+
+// rejectTTL is how long a peer that blew its PEX latency budget is refused
+const rejectTTL = 30 * time.Minute
+
+// latencyBudgetReactor wraps the PEX reactor and disconnects outbound peers
+// that do not answer our address request within the budget.  The peer handed
+// to the reactor in AddPeer records when a PexRequest is sent through it, and
+// the request is outstanding from then until the peer's PexAddrs arrives.
+// Peers we never ask are never timed.
+type latencyBudgetReactor struct {
+	p2p.Reactor
+
+	budget   time.Duration
+	rejects  *rejectCache
+	timeouts prometheus.Counter
+	logger   log.Logger
+
+	mtx     sync.Mutex
+	sw      *p2p.Switch
+	pending map[p2p.ID]time.Time
+}
+
+// newLatencyBudgetReactor wraps reactor, rejecting peers that time out for
+// rejectTTL via rejects
+func newLatencyBudgetReactor(reactor p2p.Reactor, budget time.Duration, rejects *rejectCache, chainID string, logger log.Logger) *latencyBudgetReactor {
+	return &latencyBudgetReactor{
+		Reactor: reactor,
+		budget:  budget,
+		rejects: rejects,
+		timeouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "pex",
+			Name:        "timeout_total",
+			Help:        "Number of outbound peers disconnected for not answering a PEX request within the latency budget.",
+			ConstLabels: prometheus.Labels{"chain_id": chainID},
+		}),
+		logger:  logger,
+		pending: make(map[p2p.ID]time.Time),
+	}
+}
+
+// RegisterMetrics exports tinyseed_pex_timeout_total to reg
+func (r *latencyBudgetReactor) RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(r.timeouts)
+}
+
+// SetSwitch implements p2p.Reactor
+func (r *latencyBudgetReactor) SetSwitch(sw *p2p.Switch) {
+	r.mtx.Lock()
+	r.sw = sw
+	r.mtx.Unlock()
+	r.Reactor.SetSwitch(sw)
+}
+
+// AddPeer implements p2p.Reactor
+func (r *latencyBudgetReactor) AddPeer(peer p2p.Peer) {
+	if peer.IsOutbound() {
+		peer = &requestTimedPeer{Peer: peer, reactor: r}
+	}
+	r.Reactor.AddPeer(peer)
+}
+
+// RemovePeer implements p2p.Reactor
+func (r *latencyBudgetReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	r.mtx.Lock()
+	delete(r.pending, peer.ID())
+	r.mtx.Unlock()
+	r.Reactor.RemovePeer(peer, reason)
+}
+
+// Receive implements p2p.Reactor
+func (r *latencyBudgetReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
+	var msg tmp2p.Message
+	if err := msg.Unmarshal(msgBytes); err == nil && msg.GetPexAddrs() != nil {
+		r.mtx.Lock()
+		delete(r.pending, src.ID())
+		r.mtx.Unlock()
+	}
+	r.Reactor.Receive(chID, src, msgBytes)
+}
+
+// requestSent starts the clock on id if msgBytes is a PexRequest
+func (r *latencyBudgetReactor) requestSent(id p2p.ID, msgBytes []byte) {
+	var msg tmp2p.Message
+	if err := msg.Unmarshal(msgBytes); err != nil || msg.GetPexRequest() == nil {
+		return
+	}
+	r.mtx.Lock()
+	r.pending[id] = time.Now()
+	r.mtx.Unlock()
+}
+
+// requestTimedPeer reports PexRequests sent to it to the latency budget
+type requestTimedPeer struct {
+	p2p.Peer
+
+	reactor *latencyBudgetReactor
+}
+
+// Send implements p2p.Peer
+func (p *requestTimedPeer) Send(chID byte, msgBytes []byte) bool {
+	if !p.Peer.Send(chID, msgBytes) {
+		return false
+	}
+	if chID == pex.PexChannel {
+		p.reactor.requestSent(p.ID(), msgBytes)
+	}
+	return true
+}
+
+// TrySend implements p2p.Peer
+func (p *requestTimedPeer) TrySend(chID byte, msgBytes []byte) bool {
+	if !p.Peer.TrySend(chID, msgBytes) {
+		return false
+	}
+	if chID == pex.PexChannel {
+		p.reactor.requestSent(p.ID(), msgBytes)
+	}
+	return true
+}
+
+// Run checks for overdue responses until quit is closed
+func (r *latencyBudgetReactor) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(r.budget / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.expire()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// expire disconnects every peer whose response is overdue
+func (r *latencyBudgetReactor) expire() {
+	now := time.Now()
+
+	r.mtx.Lock()
+	sw := r.sw
+	var overdue []p2p.ID
+	for id, sent := range r.pending {
+		if now.Sub(sent) > r.budget {
+			overdue = append(overdue, id)
+			delete(r.pending, id)
+		}
+	}
+	r.mtx.Unlock()
+
+	for _, id := range overdue {
+		r.timeouts.Inc()
+		r.rejects.Add(id, now.Add(rejectTTL))
+		r.logger.Info("peer exceeded PEX latency budget", "peer", id, "budget", r.budget)
+
+		if peer := sw.Peers().Get(id); peer != nil {
+			sw.StopPeerForError(peer, fmt.Errorf("no PEX response within %v", r.budget))
+		}
+	}
+}
+
+// rejectCache remembers peers we refuse to talk to for a short while
+type rejectCache struct {
+	mtx   sync.Mutex
+	until map[p2p.ID]time.Time
+}
+
+func newRejectCache() *rejectCache {
+	return &rejectCache{until: make(map[p2p.ID]time.Time)}
+}
+
+// Add rejects id until the given time
+func (c *rejectCache) Add(id p2p.ID, until time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.until[id] = until
+}
+
+// Rejected reports whether id is currently rejected, forgetting expired
+// entries as it goes
+func (c *rejectCache) Rejected(id p2p.ID) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	until, ok := c.until[id]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(c.until, id)
+		return false
+	}
+	return true
+}
+
+// FilterPeer is a p2p.PeerFilterFunc refusing rejected peers
+func (c *rejectCache) FilterPeer(_ p2p.IPeerSet, peer p2p.Peer) error {
+	if c.Rejected(peer.ID()) {
+		return fmt.Errorf("peer %s is temporarily rejected", peer.ID())
+	}
+	return nil
+}
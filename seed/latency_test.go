@@ -0,0 +1,55 @@
+package seed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/mock"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// silentReactor never sends anything to its peers
+type silentReactor struct {
+	p2p.BaseReactor
+}
+
+func (r *silentReactor) AddPeer(p2p.Peer) {}
+
+func (r *latencyBudgetReactor) isPending(id p2p.ID) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	_, ok := r.pending[id]
+	return ok
+}
+
+func TestLatencyBudgetTimesSentRequests(t *testing.T) {
+	book := newTestSeedBook(t, 1, false)
+	book.askOnConnect = true
+	pexReactor := pex.NewReactor(book, &pex.ReactorConfig{SeedMode: true})
+	r := newLatencyBudgetReactor(pexReactor, time.Second, newRejectCache(), "test", log.NewNopLogger())
+
+	peer := mock.NewPeer(nil)
+	peer.Outbound = true
+	r.AddPeer(peer)
+	if !r.isPending(peer.ID()) {
+		t.Fatal("the PexRequest sent on connect was not timed")
+	}
+
+	r.RemovePeer(peer, nil)
+	if r.isPending(peer.ID()) {
+		t.Fatal("a removed peer is still timed")
+	}
+}
+
+func TestLatencyBudgetIgnoresPeersNeverAsked(t *testing.T) {
+	r := newLatencyBudgetReactor(&silentReactor{}, time.Second, newRejectCache(), "test", log.NewNopLogger())
+
+	peer := mock.NewPeer(nil)
+	peer.Outbound = true
+	r.AddPeer(peer)
+	if r.isPending(peer.ID()) {
+		t.Fatal("a peer we never asked for addresses is timed")
+	}
+}
@@ -0,0 +1,81 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	tmnet "github.com/tendermint/tendermint/libs/net"
+)
+
+// ResolveListenAddress turns laddr, which may list several comma separated
+// addresses, into the single address the transport listens on.
+//
+// The tendermint v0.34 switch drives exactly one transport with one
+// listener, so several addresses are only accepted when that listener can
+// serve each of them: wildcard addresses of either family sharing a port,
+// e.g. tcp://0.0.0.0:36656,tcp://[::]:36656.  They are served on the IPv4
+// wildcard, which Go opens as a dual-stack socket where the host has IPv6 and
+// as a plain IPv4 socket where it does not, so hosts with IPv6 disabled
+// still accept IPv4.  Hosts with IPv6 that cannot accept IPv4 on an IPv6
+// socket are refused, as no single listener serves both families there.
+func ResolveListenAddress(laddr string) (string, error) {
+	addrs := strings.Split(laddr, ",")
+	if len(addrs) == 1 {
+		return strings.TrimSpace(laddr), nil
+	}
+
+	var port string
+	ipv6 := false
+	for _, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		protocol, address := tmnet.ProtocolAndAddress(addr)
+		if protocol != "tcp" {
+			return "", fmt.Errorf("laddr %q: only tcp addresses can be combined", addr)
+		}
+		host, p, err := net.SplitHostPort(address)
+		if err != nil {
+			return "", fmt.Errorf("laddr %q: %w", addr, err)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsUnspecified() {
+			return "", fmt.Errorf("laddr %q: only wildcard addresses sharing a port can be combined, the switch has a single listener", addr)
+		}
+		if port != "" && p != port {
+			return "", fmt.Errorf("laddr %q: every address must use port %s, the switch has a single listener", addr, port)
+		}
+		port = p
+		if ip.To4() == nil {
+			ipv6 = true
+		}
+	}
+	if ipv6 {
+		if err := checkDualStack(); err != nil {
+			return "", fmt.Errorf("laddr %q: %w", laddr, err)
+		}
+	}
+	return "tcp://" + net.JoinHostPort("0.0.0.0", port), nil
+}
+
+// checkDualStack makes sure a socket on the IPv4 wildcard also accepts IPv6,
+// or that the host has no IPv6 to accept
+func checkDualStack() error {
+	ln, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	// Go reports the IPv6 wildcard when it opened a dual-stack socket
+	if ln.Addr().(*net.TCPAddr).IP.To4() == nil {
+		return nil
+	}
+
+	ln6, err := net.Listen("tcp6", "[::]:0")
+	if err != nil {
+		// no IPv6 on this host
+		return nil
+	}
+	ln6.Close()
+	return errors.New("this host cannot accept IPv4 and IPv6 on one socket, and the switch has a single listener")
+}
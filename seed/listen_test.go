@@ -0,0 +1,56 @@
+package seed
+
+import (
+	"net"
+	"testing"
+
+	tmnet "github.com/tendermint/tendermint/libs/net"
+)
+
+func TestResolveListenAddressServesEachFamily(t *testing.T) {
+	port := freePort(t)
+	laddr, err := ResolveListenAddress("tcp://0.0.0.0:" + port + ", tcp://[::]:" + port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, address := tmnet.ProtocolAndAddress(laddr)
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	dial := func(host string) {
+		c, err := net.Dial("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			t.Errorf("%s is not served: %v", host, err)
+			return
+		}
+		c.Close()
+	}
+	dial("127.0.0.1")
+	if ln6, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		ln6.Close()
+		dial("::1")
+	}
+}
+
+func TestResolveListenAddressRejectsSeveralListeners(t *testing.T) {
+	for _, laddr := range []string{
+		"tcp://0.0.0.0:36656,tcp://[::]:36657",
+		"tcp://127.0.0.1:36656,tcp://[::]:36656",
+		"unix:///tmp/seed.sock,tcp://0.0.0.0:36656",
+	} {
+		if _, err := ResolveListenAddress(laddr); err == nil {
+			t.Errorf("%s was accepted", laddr)
+		}
+	}
+}
+
+func freePort(t *testing.T) string {
+	_, port, err := net.SplitHostPort(freeAddress(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return port
+}
@@ -0,0 +1,102 @@
+package seed
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// Values of Config.LogOutput besides a file path
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+)
+
+// defaultLogLevel applies to modules log_level does not mention
+const defaultLogLevel = "info"
+
+// Values of Config.LogFormat
+const (
+	LogFormatPlain = "plain"
+	LogFormatJSON  = "json"
+)
+
+// NewLogger writes logs to w in format
+func NewLogger(w io.Writer, format string) (log.Logger, error) {
+	switch format {
+	case "", LogFormatPlain:
+		return log.NewTMLogger(log.NewSyncWriter(w)), nil
+	case LogFormatJSON:
+		return log.NewTMJSONLogger(log.NewSyncWriter(w)), nil
+	default:
+		return nil, fmt.Errorf("log_format must be %q or %q, got %q", LogFormatPlain, LogFormatJSON, format)
+	}
+}
+
+// OpenLogOutput returns the writer LogOutput refers to.  Log files are
+// reopened on SIGHUP so that they can be rotated.
+func OpenLogOutput(output string) (io.Writer, error) {
+	switch output {
+	case "", LogOutputStdout:
+		return os.Stdout, nil
+	case LogOutputStderr:
+		return os.Stderr, nil
+	}
+
+	if !filepath.IsAbs(output) {
+		return nil, fmt.Errorf("log_output must be %q, %q or an absolute path, got %q", LogOutputStdout, LogOutputStderr, output)
+	}
+	f, err := openLogFile(output)
+	if err != nil {
+		return nil, err
+	}
+	w := &reopeningFile{path: output, file: f}
+	go w.reopenOnHangup()
+	return w, nil
+}
+
+// reopeningFile is a log file that is reopened on SIGHUP
+type reopeningFile struct {
+	path string
+
+	mtx  sync.Mutex
+	file *os.File
+}
+
+// Write implements io.Writer
+func (w *reopeningFile) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.file.Write(p)
+}
+
+// reopenOnHangup reopens the file every time the process receives SIGHUP.
+// If reopening fails, logging carries on into the old file.
+func (w *reopeningFile) reopenOnHangup() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		f, err := openLogFile(w.path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to reopen log file %s: %v\n", w.path, err)
+			continue
+		}
+
+		w.mtx.Lock()
+		old := w.file
+		w.file = f
+		w.mtx.Unlock()
+		old.Close()
+	}
+}
+
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
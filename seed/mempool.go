@@ -0,0 +1,37 @@
+package seed
+
+import (
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// MempoolChannel is the channel tendermint's mempool reactor gossips
+// transactions on
+const MempoolChannel = byte(0x30)
+
+// mempoolReactor claims the mempool channel so the seed can advertise it to
+// nodes that look for it.  The seed has no mempool, so every transaction it
+// receives is dropped.
+type mempoolReactor struct {
+	p2p.BaseReactor
+}
+
+func newMempoolReactor() *mempoolReactor {
+	r := &mempoolReactor{}
+	r.BaseReactor = *p2p.NewBaseReactor("Mempool", r)
+	return r
+}
+
+// GetChannels implements p2p.Reactor
+func (r *mempoolReactor) GetChannels() []*conn.ChannelDescriptor {
+	return []*conn.ChannelDescriptor{
+		{
+			ID:                  MempoolChannel,
+			Priority:            5,
+			RecvMessageCapacity: 1024 * 1024,
+		},
+	}
+}
+
+// Receive implements p2p.Reactor by discarding the message
+func (r *mempoolReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {}
@@ -0,0 +1,125 @@
+package seed
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	tmed25519 "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// chainIDHashPrefixLen is how many bytes of sha256(chainID) are mixed into
+// the node key
+const chainIDHashPrefixLen = 8
+
+// NamespaceNodeKey derives a chain specific node key by XORing the first 8
+// bytes of sha256(chainID) into the private key seed before the public key is
+// derived, so the same key file yields a different node ID on every chain.
+//
+// This is a non-standard extension: the key on disk is left untouched, and
+// any tooling that wants to predict our node ID must apply the same
+// convention.
+func NamespaceNodeKey(nodeKey *p2p.NodeKey, chainID string) (*p2p.NodeKey, error) {
+	privKey, ok := nodeKey.PrivKey.(tmed25519.PrivKey)
+	if !ok {
+		return nil, errUnsupportedKeyType(nodeKey)
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	copy(seed, ed25519.PrivateKey(privKey).Seed())
+
+	prefix := sha256.Sum256([]byte(chainID))
+	for i := 0; i < chainIDHashPrefixLen; i++ {
+		seed[i] ^= prefix[i]
+	}
+
+	return &p2p.NodeKey{PrivKey: tmed25519.PrivKey(ed25519.NewKeyFromSeed(seed))}, nil
+}
+
+// errUnsupportedKeyType reports a node key we cannot derive from
+func errUnsupportedKeyType(nodeKey *p2p.NodeKey) error {
+	return fmt.Errorf("unsupported node key type %s", nodeKey.PrivKey.Type())
+}
+
+// LoadOrRecoverNodeKey behaves like p2p.LoadOrGenNodeKey, except that an
+// unreadable key file is moved aside to <path>.corrupt-<timestamp> and
+// replaced with a freshly generated key
+func LoadOrRecoverNodeKey(path string, logger log.Logger) (*p2p.NodeKey, error) {
+	nodeKey, err := p2p.LoadOrGenNodeKey(path)
+	if err == nil {
+		return nodeKey, nil
+	}
+
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		// nothing to recover from, e.g. the directory is not accessible
+		return nil, err
+	}
+
+	corruptPath := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+	if err := os.Rename(path, corruptPath); err != nil {
+		return nil, err
+	}
+	logger.Error("node key is corrupted, generating a new identity",
+		"err", err, "old-id", partialNodeID(data), "moved-to", corruptPath)
+
+	return p2p.LoadOrGenNodeKey(path)
+}
+
+// partialNodeID digs the private key out of a damaged node_key.json and
+// returns the node ID it belonged to, or "unknown"
+func partialNodeID(data []byte) p2p.ID {
+	data = bytes.ReplaceAll(data, []byte(" "), nil)
+	marker := []byte(`"value":"`)
+	start := bytes.Index(data, marker)
+	if start < 0 {
+		return "unknown"
+	}
+	value := data[start+len(marker):]
+	if end := bytes.IndexByte(value, '"'); end >= 0 {
+		value = value[:end]
+	}
+
+	privKey, err := base64.StdEncoding.DecodeString(string(value))
+	if err != nil || len(privKey) != ed25519.PrivateKeySize {
+		return "unknown"
+	}
+	return p2p.PubKeyToID(tmed25519.PrivKey(privKey).PubKey())
+}
+
+// LoadSeedNodeKey returns the key the seed for SeedConfig identifies itself
+// with, from the remote signer or the configured key manager
+func LoadSeedNodeKey(SeedConfig Config, logger log.Logger) (*p2p.NodeKey, error) {
+	var nodeKey *p2p.NodeKey
+	if SeedConfig.RemoteSignerAddress != "" {
+		signer, err := NewRemoteSigner(SeedConfig.RemoteSignerAddress)
+		if err != nil {
+			return nil, err
+		}
+		nodeKey = &p2p.NodeKey{PrivKey: signer}
+	} else {
+		keyManager, err := LoadKeyManager(SeedConfig.KeyManagerPlugin)
+		if err != nil {
+			return nil, err
+		}
+		if fileKeys, ok := keyManager.(FileKeyManager); ok {
+			fileKeys.Logger = logger
+			keyManager = fileKeys
+		}
+		nodeKey, err = keyManager.LoadKey(SeedConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if SeedConfig.ChainIDHashPrefix {
+		return NamespaceNodeKey(nodeKey, SeedConfig.ChainID)
+	}
+	return nodeKey, nil
+}
@@ -0,0 +1,267 @@
+package seed
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	bolt "go.etcd.io/bbolt"
+)
+
+// peerHistoryBucket holds a JSON encoded PeerHistory per node ID
+var peerHistoryBucket = []byte("peers")
+
+// Updates are queued and written in one transaction every
+// peerHistoryFlushInterval, or once peerHistoryMaxPending are queued, so
+// that a large PEX response does not wait for a disk sync per address
+const (
+	peerHistoryFlushInterval = time.Second
+	peerHistoryMaxPending    = 512
+	peerHistoryQueueSize     = 4096
+)
+
+// peerHistoryOpenTimeout is how long to wait for the lock a running seed
+// holds on its database
+const peerHistoryOpenTimeout = time.Second
+
+// PeerHistory is everything recorded about a node over the seed's
+// lifetime
+type PeerHistory struct {
+	ID            p2p.ID    `json:"id"`
+	Address       string    `json:"address"`
+	Source        string    `json:"source,omitempty"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	LastSuccess   time.Time `json:"last_success"`
+	Successes     int       `json:"successes"`
+	DialFailures  int       `json:"dial_failures"`
+	Connections   int       `json:"connections"`
+	LastConnected time.Time `json:"last_connected"`
+	Moniker       string    `json:"moniker,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	Network       string    `json:"network,omitempty"`
+}
+
+// peerHistoryUpdate changes the record of id for something that happened
+// at the given time
+type peerHistoryUpdate struct {
+	id     p2p.ID
+	at     time.Time
+	update func(record *PeerHistory, at time.Time)
+}
+
+// peerHistory records what the seed learns about every node into a bbolt
+// database at path.  Nothing is recorded until Open.
+type peerHistory struct {
+	path   string
+	logger log.Logger
+
+	db      *bolt.DB
+	updates chan peerHistoryUpdate
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func newPeerHistory(path string, logger log.Logger) *peerHistory {
+	return &peerHistory{
+		path:    path,
+		logger:  logger,
+		updates: make(chan peerHistoryUpdate, peerHistoryQueueSize),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Open opens the database and starts writing updates to it
+func (h *peerHistory) Open() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), os.ModePerm); err != nil {
+		return err
+	}
+	db, err := bolt.Open(h.path, 0600, &bolt.Options{Timeout: peerHistoryOpenTimeout})
+	if err != nil {
+		return err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(peerHistoryBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return err
+	}
+
+	h.db = db
+	go h.run()
+	return nil
+}
+
+// Close writes the queued updates and closes the database
+func (h *peerHistory) Close() error {
+	if h == nil || h.db == nil {
+		return nil
+	}
+	close(h.quit)
+	<-h.done
+	return h.db.Close()
+}
+
+func (h *peerHistory) run() {
+	defer close(h.done)
+
+	ticker := time.NewTicker(peerHistoryFlushInterval)
+	defer ticker.Stop()
+
+	var pending []peerHistoryUpdate
+	for {
+		select {
+		case u := <-h.updates:
+			pending = append(pending, u)
+			if len(pending) < peerHistoryMaxPending {
+				continue
+			}
+		case <-ticker.C:
+		case <-h.quit:
+			for len(h.updates) > 0 {
+				pending = append(pending, <-h.updates)
+			}
+			h.flush(pending)
+			return
+		}
+		h.flush(pending)
+		pending = pending[:0]
+	}
+}
+
+// flush applies pending in a single transaction
+func (h *peerHistory) flush(pending []peerHistoryUpdate) {
+	if len(pending) == 0 {
+		return
+	}
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(peerHistoryBucket)
+		for _, u := range pending {
+			var record PeerHistory
+			if bz := bucket.Get([]byte(u.id)); bz != nil {
+				if err := json.Unmarshal(bz, &record); err != nil {
+					return err
+				}
+			}
+			if record.FirstSeen.IsZero() {
+				record.ID = u.id
+				record.FirstSeen = u.at
+			}
+			u.update(&record, u.at)
+
+			bz, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(u.id), bz); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("failed to record peer history", "updates", len(pending), "err", err)
+	}
+}
+
+// record queues update for id.  Updates are dropped rather than blocking
+// the caller when the queue is full.
+func (h *peerHistory) record(id p2p.ID, update func(record *PeerHistory, at time.Time)) {
+	if h == nil {
+		return
+	}
+	select {
+	case h.updates <- peerHistoryUpdate{id: id, at: time.Now(), update: update}:
+	default:
+		h.logger.Debug("peer history queue full, dropping update", "id", id)
+	}
+}
+
+// heard records that addr was handed to us by src
+func (h *peerHistory) heard(addr, src *p2p.NetAddress) {
+	h.record(addr.ID, func(record *PeerHistory, at time.Time) {
+		record.Address = addr.String()
+		record.LastSeen = at
+		if record.Source == "" && src != nil && src.ID != addr.ID {
+			record.Source = src.String()
+		}
+	})
+}
+
+// dialed records a dial of id and whether it succeeded
+func (h *peerHistory) dialed(id p2p.ID, ok bool) {
+	h.record(id, func(record *PeerHistory, at time.Time) {
+		if ok {
+			record.LastSeen = at
+			record.LastSuccess = at
+			record.Successes++
+		} else {
+			record.DialFailures++
+		}
+	})
+}
+
+// FilterPeer is a p2p.PeerFilterFunc recording what peer advertised in its
+// handshake.  It never rejects.
+func (h *peerHistory) FilterPeer(_ p2p.IPeerSet, peer p2p.Peer) error {
+	info, _ := peer.NodeInfo().(p2p.DefaultNodeInfo)
+	h.record(peer.ID(), func(record *PeerHistory, at time.Time) {
+		if record.Address == "" {
+			record.Address = peer.SocketAddr().String()
+		}
+		record.LastSeen = at
+		record.LastConnected = at
+		record.Connections++
+		record.Moniker = info.Moniker
+		record.Version = info.Version
+		record.Network = info.Network
+	})
+	return nil
+}
+
+// All returns every record, most recently seen first
+func (h *peerHistory) All() ([]PeerHistory, error) {
+	return readPeerHistory(h.db)
+}
+
+// LoadPeerHistory reads every record of the database at path.  It fails
+// with bolt.ErrTimeout while a running seed holds the database.
+func LoadPeerHistory(path string) ([]PeerHistory, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: peerHistoryOpenTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return readPeerHistory(db)
+}
+
+func readPeerHistory(db *bolt.DB) ([]PeerHistory, error) {
+	var records []PeerHistory
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(peerHistoryBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, bz []byte) error {
+			var record PeerHistory
+			if err := json.Unmarshal(bz, &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LastSeen.After(records[j].LastSeen)
+	})
+	return records, err
+}
@@ -0,0 +1,138 @@
+package seed
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// Bucket types reported by the peers API
+const (
+	bucketNew = "new"
+	bucketOld = "old"
+)
+
+// PeerEntry is an address book entry as served by GET /peers
+type PeerEntry struct {
+	ID          p2p.ID    `json:"id"`
+	Address     string    `json:"address"`
+	Bucket      string    `json:"bucket"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastSuccess time.Time `json:"last_success"`
+	Attempts    int       `json:"attempts"`
+	Successes   int       `json:"successes"`
+	Country     string    `json:"country,omitempty"`
+	Continent   string    `json:"continent,omitempty"`
+}
+
+// ChainPeers are the entries of a single chain's address book
+type ChainPeers struct {
+	ChainID string      `json:"chain_id"`
+	Peers   []PeerEntry `json:"peers"`
+}
+
+// ChainHistory is a single chain's peer history as served by GET /history
+type ChainHistory struct {
+	ChainID string        `json:"chain_id"`
+	Peers   []PeerHistory `json:"peers"`
+}
+
+// peerEntries lists book's entries, most recently successful first
+func peerEntries(book *seedBook) []PeerEntry {
+	candidates := book.candidates()
+	entries := make([]PeerEntry, 0, len(candidates))
+	for _, ka := range candidates {
+		// tendermint moves an address to an old bucket once it has been
+		// marked good, which is what IsGood reports
+		bucket := bucketNew
+		if book.IsGood(ka.Addr) {
+			bucket = bucketOld
+		}
+		entries = append(entries, PeerEntry{
+			ID:          ka.Addr.ID,
+			Address:     ka.Addr.String(),
+			Bucket:      bucket,
+			LastSeen:    ka.LastSeen,
+			LastSuccess: ka.LastSuccess,
+			Attempts:    ka.Attempts,
+			Successes:   ka.Successes,
+			Country:     ka.Country,
+			Continent:   ka.Continent,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastSuccess.After(entries[j].LastSuccess)
+	})
+	return entries
+}
+
+// StartPeersServer serves the address books of every chain, keyed by chain
+// ID, on addr.  GET /peers returns every chain and GET /peers?chain_id=...
+// a single one.  GET /history does the same for the peer history.
+func StartPeersServer(addr string, books map[string]*seedBook) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/peers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var resp []ChainPeers
+		if chainID := r.URL.Query().Get("chain_id"); chainID != "" {
+			book, ok := books[chainID]
+			if !ok {
+				http.Error(w, "unknown chain_id", http.StatusNotFound)
+				return
+			}
+			resp = append(resp, ChainPeers{ChainID: chainID, Peers: peerEntries(book)})
+		} else {
+			for chainID, book := range books {
+				resp = append(resp, ChainPeers{ChainID: chainID, Peers: peerEntries(book)})
+			}
+			sort.Slice(resp, func(i, j int) bool {
+				return resp[i].ChainID < resp[j].ChainID
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var resp []ChainHistory
+		for chainID, book := range books {
+			if want := r.URL.Query().Get("chain_id"); want != "" && want != chainID {
+				continue
+			}
+			if book.history == nil {
+				continue
+			}
+			records, err := book.history.All()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp = append(resp, ChainHistory{ChainID: chainID, Peers: records})
+		}
+		if len(resp) == 0 {
+			http.Error(w, "no peer history for this chain_id, is peer_history_file set?", http.StatusNotFound)
+			return
+		}
+		sort.Slice(resp, func(i, j int) bool {
+			return resp[i].ChainID < resp[j].ChainID
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+	return serveHTTP(addr, mux)
+}
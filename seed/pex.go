@@ -0,0 +1,560 @@
+package seed
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmmath "github.com/tendermint/tendermint/libs/math"
+	tmstrings "github.com/tendermint/tendermint/libs/strings"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// minSelfBroadcastSelection is the selection size below which the seed
+// appends its own address to a PEX response
+const minSelfBroadcastSelection = 3
+
+// seedBook wraps the address book handed to the PEX reactor so we can shape
+// the addresses served to peers.  In seed mode the reactor only calls
+// GetSelectionWithBias when answering an inbound PEX request, so everything
+// else passes straight through to the real book.
+type seedBook struct {
+	pex.AddrBook
+
+	// selfAddr is appended to thin selections when SeedModeBroadcastSelf is set
+	selfAddr *p2p.NetAddress
+
+	sampler SamplingStrategy
+
+	// gossipTopN restricts responses to the best scored addresses when
+	// PeerScoreEnabled is set
+	gossipTopN int
+
+	// geo replaces the sampler when AddrBookGeographicPreference is set
+	geo *geoSelector
+
+	// geoip tags known addresses with their location (nil without a
+	// GeoIP database)
+	geoip *GeoIP
+
+	// verifiedWithin makes responses prefer addresses the crawler reached
+	// this recently (0 when the crawler is off)
+	verifiedWithin time.Duration
+
+	// access keeps denied addresses out of the book and out of responses
+	// (nil without an access list)
+	access *accessListFilter
+
+	// history records what we learn about every node (nil unless
+	// PeerHistoryFile is set)
+	history *peerHistory
+
+	// lastPEX is when we last answered a PEX request or were handed an
+	// address, in unix nanoseconds, and pexEvents how often that happened
+	// (both atomic)
+	lastPEX   int64
+	pexEvents uint64
+
+	// metrics is nil unless prometheus is enabled
+	metrics *seedMetrics
+
+	// seedIDs are the IDs from Config.Seeds and SeedsURL, of which at most
+	// maxSeedAddrs are handed out per response
+	seedsMtx     sync.RWMutex
+	seedIDs      map[p2p.ID]struct{}
+	maxSeedAddrs int
+
+	// which kinds of addresses we pass on to peers
+	reachability string
+	relayOnion   bool
+	relayPrivate bool
+
+	// files are where the book is stored, one per shard.  Every save
+	// publishes them from tendermint's working files and backs them up,
+	// keeping the backups newest copies of each.
+	files   []string
+	backups int
+
+	logger log.Logger
+
+	// warmUp suspends eviction while a fresh book fills up
+	warmUp *warmUp
+
+	// askOnConnect makes the reactor ask every outbound peer for addresses
+	// when it connects, not only while the book is short of them
+	askOnConnect bool
+
+	// writer applies AddAddress calls in the background when
+	// ConcurrentAddressBookWrites is set
+	writer *addrWriter
+
+	mtx   sync.Mutex
+	known map[p2p.ID]*KnownAddress
+
+	// selectionTTL is how long a computed selection is served again before
+	// the book is asked for a new one (0 disables caching), and
+	// selectionMaxChanges how many book changes cut that short
+	selectionTTL        time.Duration
+	selectionMaxChanges int
+	cacheMtx            sync.Mutex
+	cachedAt            time.Time
+	cached              []*p2p.NetAddress
+	changes             int
+}
+
+// newSeedBook wraps book with the PEX response policies from SeedConfig
+func newSeedBook(book pex.AddrBook, SeedConfig Config, selfAddr *p2p.NetAddress) (*seedBook, error) {
+	sampler, err := NewSamplingStrategy(SeedConfig.PeerSamplingAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := loadKnownAddresses(AddrBookFiles(SeedConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	seedIDs := make(map[p2p.ID]struct{})
+	for _, seed := range tmstrings.SplitAndTrim(SeedConfig.Seeds, ",", " ") {
+		if addr, err := p2p.NewNetAddressString(seed); err == nil {
+			seedIDs[addr.ID] = struct{}{}
+		}
+	}
+
+	b := &seedBook{
+		AddrBook:            book,
+		sampler:             sampler,
+		seedIDs:             seedIDs,
+		maxSeedAddrs:        SeedConfig.MaxSeedAddressesInPEX,
+		reachability:        SeedConfig.NetworkReachabilityMode,
+		relayOnion:          SeedConfig.PEXRelayOnionAddresses,
+		relayPrivate:        SeedConfig.PEXRelayPrivateAddresses,
+		files:               AddrBookFiles(SeedConfig),
+		backups:             SeedConfig.AddrBookBackups,
+		logger:              log.NewNopLogger(),
+		warmUp:              &warmUp{},
+		known:               known,
+		selectionTTL:        SeedConfig.PeerListCacheTTL,
+		selectionMaxChanges: SeedConfig.PeerListCacheMaxChanges,
+		lastPEX:             time.Now().UnixNano(),
+	}
+	if SeedConfig.SeedModeBroadcastSelf {
+		b.selfAddr = selfAddr
+	}
+	if SeedConfig.PeerScoreEnabled {
+		b.gossipTopN = SeedConfig.PeerGossipTopN
+	}
+	if SeedConfig.ConcurrentAddressBookWrites {
+		// tendermint's book takes a single lock, so only shards can be
+		// written to in parallel
+		workers := 1
+		if balancer, ok := book.(*SeedBalancer); ok {
+			workers = tmmath.MinInt(SeedConfig.AddressBookWriteWorkers, len(balancer.shards))
+		}
+		b.writer = startAddrWriter(workers, func(addr, src *p2p.NetAddress) {
+			if err := b.addAddress(addr, src); err != nil {
+				b.logger.Debug("Failed to add new address", "addr", addr, "err", err)
+			}
+		})
+	}
+	return b, nil
+}
+
+// AddAddress implements pex.AddrBook.  With concurrent writes enabled the
+// address is queued and errors are only logged; use addAddress to learn
+// whether the book took it.
+func (b *seedBook) AddAddress(addr *p2p.NetAddress, src *p2p.NetAddress) error {
+	b.touchPEX()
+	if b.writer != nil && addr != nil && src != nil {
+		b.writer.Add(addr, src)
+		return nil
+	}
+	return b.addAddress(addr, src)
+}
+
+// addAddress adds addr to the book and records when we last heard of it
+func (b *seedBook) addAddress(addr *p2p.NetAddress, src *p2p.NetAddress) error {
+	if b.access != nil && addr != nil && !b.access.Permits(addr) {
+		return fmt.Errorf("%v is not permitted by the access list", addr)
+	}
+	err := b.AddrBook.AddAddress(addr, src)
+	if err == nil {
+		b.mtx.Lock()
+		b.knownAddress(addr).LastSeen = time.Now()
+		b.mtx.Unlock()
+		b.bookChanged()
+		b.history.heard(addr, src)
+	}
+	return err
+}
+
+// RemoveAddress implements pex.AddrBook
+func (b *seedBook) RemoveAddress(addr *p2p.NetAddress) {
+	b.AddrBook.RemoveAddress(addr)
+
+	b.mtx.Lock()
+	delete(b.known, addr.ID)
+	b.mtx.Unlock()
+	b.bookChanged()
+}
+
+// MarkGood implements pex.AddrBook
+func (b *seedBook) MarkGood(id p2p.ID) {
+	b.AddrBook.MarkGood(id)
+	b.history.dialed(id, true)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if ka, ok := b.known[id]; ok {
+		now := time.Now()
+		ka.LastSeen = now
+		ka.LastSuccess = now
+		ka.Successes++
+	}
+}
+
+// MarkAttempt implements pex.AddrBook.  The PEX reactor only marks
+// attempts that failed.
+func (b *seedBook) MarkAttempt(addr *p2p.NetAddress) {
+	b.metrics.dialFailed()
+	b.markAttempt(addr)
+}
+
+// markAttempt records a failed attempt to reach addr without counting it as
+// a failed dial, for the crawler's probes
+func (b *seedBook) markAttempt(addr *p2p.NetAddress) {
+	b.AddrBook.MarkAttempt(addr)
+	b.history.dialed(addr.ID, false)
+
+	b.mtx.Lock()
+	b.knownAddress(addr).Attempts++
+	b.mtx.Unlock()
+}
+
+// Save implements pex.AddrBook.  tendermint saves to its working files,
+// which are then published with the version stamp and backed up.
+func (b *seedBook) Save() {
+	b.AddrBook.Save()
+	for _, path := range b.files {
+		if err := publishAddrBook(path); err != nil {
+			b.logger.Error("Failed to save address book", "file", path, "err", err)
+			continue
+		}
+		if err := BackupAddrBook(path, b.backups); err != nil {
+			b.logger.Error("Failed to back up address book", "file", path, "err", err)
+		}
+	}
+}
+
+// Stop implements service.Service.  It returns once tendermint has saved
+// the book for the last time.
+func (b *seedBook) Stop() error {
+	if b.writer != nil {
+		b.writer.Stop()
+	}
+	if err := b.AddrBook.Stop(); err != nil {
+		return err
+	}
+	waitAddrBook(b.AddrBook)
+	return nil
+}
+
+// SetLogger implements service.Service
+func (b *seedBook) SetLogger(logger log.Logger) {
+	b.logger = logger
+	b.AddrBook.SetLogger(logger)
+}
+
+// NeedMoreAddrs implements pex.AddrBook.  In seed mode the reactor only
+// asks when an outbound peer connects, so askOnConnect sends every request
+// through the peer handed to the reactor's AddPeer rather than leaving it to
+// the crawl, which uses the switch's own peer.
+func (b *seedBook) NeedMoreAddrs() bool {
+	return b.askOnConnect || b.AddrBook.NeedMoreAddrs()
+}
+
+// WarmingUp reports whether eviction should hold off.  The crawler is the
+// seed's only eviction policy; removals for the access list still apply.
+func (b *seedBook) WarmingUp() bool {
+	return b.warmUp.Active()
+}
+
+// knownAddress returns the entry for addr, creating it if needed.  The caller
+// must hold mtx.
+func (b *seedBook) knownAddress(addr *p2p.NetAddress) *KnownAddress {
+	ka, ok := b.known[addr.ID]
+	if !ok {
+		ka = &KnownAddress{Addr: addr}
+		b.tagLocation(ka)
+		b.known[addr.ID] = ka
+	}
+	return ka
+}
+
+// setGeoIP tags every known address, and those added later, with its
+// location in geoip
+func (b *seedBook) setGeoIP(geoip *GeoIP) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.geoip = geoip
+	for _, ka := range b.known {
+		b.tagLocation(ka)
+	}
+}
+
+// tagLocation sets the country and continent of ka.  b.mtx must be held.
+func (b *seedBook) tagLocation(ka *KnownAddress) {
+	if b.geoip == nil {
+		return
+	}
+	loc, _ := b.geoip.Lookup(ka.Addr.IP)
+	ka.Country, ka.Continent = loc.Country, loc.Continent
+}
+
+// countServed reports the locations of addrs, a PEX response, to the
+// metrics
+func (b *seedBook) countServed(addrs []*p2p.NetAddress) {
+	if b.metrics == nil || b.geoip == nil {
+		return
+	}
+
+	b.mtx.Lock()
+	locs := make([]GeoLocation, 0, len(addrs))
+	for _, addr := range addrs {
+		var loc GeoLocation
+		if ka, ok := b.known[addr.ID]; ok {
+			loc.Country, loc.Continent = ka.Country, ka.Continent
+		}
+		locs = append(locs, loc)
+	}
+	b.mtx.Unlock()
+	b.metrics.servedAddrs(locs)
+}
+
+// candidates returns the known addresses that are still in the book
+func (b *seedBook) candidates() []KnownAddress {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	candidates := make([]KnownAddress, 0, len(b.known))
+	for id, ka := range b.known {
+		if !b.AddrBook.HasAddress(ka.Addr) || b.AddrBook.IsBanned(ka.Addr) {
+			delete(b.known, id)
+			continue
+		}
+		candidates = append(candidates, *ka)
+	}
+	return candidates
+}
+
+// Addresses returns every address currently in the book
+func (b *seedBook) Addresses() []*p2p.NetAddress {
+	candidates := b.candidates()
+	addrs := make([]*p2p.NetAddress, 0, len(candidates))
+	for _, ka := range candidates {
+		addrs = append(addrs, ka.Addr)
+	}
+	return addrs
+}
+
+// GetSelectionWithBias implements pex.AddrBook.  With PeerListCacheTTL set,
+// the last selection is served again until it expires or the book has
+// changed PeerListCacheMaxChanges times, sparing the book's lock under
+// bursts of PEX requests.
+func (b *seedBook) GetSelectionWithBias(biasTowardsNewAddrs int) []*p2p.NetAddress {
+	b.metrics.served()
+	b.touchPEX()
+	addrs := b.cachedSelection(biasTowardsNewAddrs)
+	b.countServed(addrs)
+	return addrs
+}
+
+// cachedSelection returns the cached selection, computing a new one if it
+// is missing or expired
+func (b *seedBook) cachedSelection(biasTowardsNewAddrs int) []*p2p.NetAddress {
+	if b.selectionTTL <= 0 {
+		return b.selectFrom(b.AddrBook, nil, biasTowardsNewAddrs)
+	}
+
+	b.cacheMtx.Lock()
+	defer b.cacheMtx.Unlock()
+	if b.cached == nil || time.Since(b.cachedAt) > b.selectionTTL {
+		b.cached = b.selectFrom(b.AddrBook, nil, biasTowardsNewAddrs)
+		b.cachedAt = time.Now()
+		b.changes = 0
+	}
+	return b.cached
+}
+
+// touchPEX records PEX activity
+func (b *seedBook) touchPEX() {
+	atomic.StoreInt64(&b.lastPEX, time.Now().UnixNano())
+	atomic.AddUint64(&b.pexEvents, 1)
+}
+
+// PEXEvents returns how many PEX requests were answered and addresses
+// received since the book was created
+func (b *seedBook) PEXEvents() uint64 {
+	return atomic.LoadUint64(&b.pexEvents)
+}
+
+// LastPEXActivity returns when we last answered a PEX request or were
+// handed an address, or when the book was created if neither happened yet
+func (b *seedBook) LastPEXActivity() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&b.lastPEX))
+}
+
+// bookChanged counts an address added or removed, dropping the cached
+// selection once there were selectionMaxChanges of them
+func (b *seedBook) bookChanged() {
+	if b.selectionTTL <= 0 {
+		return
+	}
+	b.cacheMtx.Lock()
+	b.changes++
+	if b.changes >= b.selectionMaxChanges {
+		b.cached = nil
+	}
+	b.cacheMtx.Unlock()
+}
+
+// invalidateSelection drops the cached selection
+func (b *seedBook) invalidateSelection() {
+	if b.selectionTTL <= 0 {
+		return
+	}
+	b.cacheMtx.Lock()
+	b.cached = nil
+	b.cacheMtx.Unlock()
+}
+
+// SelectionFor answers a PEX request from the peer with the given ID at ip.
+// With a sharded book the response only draws from the requester's shard,
+// and with GeoPreferenceRequester it favours addresses close to ip.  Neither
+// is cached.
+func (b *seedBook) SelectionFor(id p2p.ID, ip net.IP, biasTowardsNewAddrs int) []*p2p.NetAddress {
+	book := b.AddrBook
+	if balancer, ok := b.AddrBook.(*SeedBalancer); ok {
+		book = balancer.Shard(id)
+	} else if !b.geo.perRequester() {
+		return b.GetSelectionWithBias(biasTowardsNewAddrs)
+	}
+
+	b.metrics.served()
+	b.touchPEX()
+	addrs := b.selectFrom(book, ip, biasTowardsNewAddrs)
+	b.countServed(addrs)
+	return addrs
+}
+
+// selectFrom computes a PEX response from book, which is either the wrapped
+// book or one of its shards, according to the seed's policies.  requester
+// is the address of the peer asking, nil if unknown.
+func (b *seedBook) selectFrom(book pex.AddrBook, requester net.IP, biasTowardsNewAddrs int) []*p2p.NetAddress {
+	addrs := book.GetSelectionWithBias(biasTowardsNewAddrs)
+
+	// keep the book's idea of how many addresses to hand out, but let the
+	// configured strategy decide which ones
+	if candidates := b.candidates(); len(candidates) >= len(addrs) {
+		relayable := candidates[:0]
+		for _, ka := range candidates {
+			if b.relay(ka.Addr) && (book == b.AddrBook || book.HasAddress(ka.Addr)) {
+				relayable = append(relayable, ka)
+			}
+		}
+		if b.verifiedWithin > 0 {
+			relayable = preferVerified(relayable, len(addrs), b.verifiedWithin)
+		}
+		if b.gossipTopN > 0 {
+			relayable = topScored(relayable, b.gossipTopN)
+		}
+
+		var sample []KnownAddress
+		if b.geo != nil {
+			sample = b.geo.Select(relayable, len(addrs), requester)
+		} else {
+			sample = b.sampler.Sample(relayable, len(addrs))
+		}
+		addrs = make([]*p2p.NetAddress, 0, len(sample))
+		for _, ka := range sample {
+			addrs = append(addrs, ka.Addr)
+		}
+	} else {
+		relayable := addrs[:0]
+		for _, addr := range addrs {
+			if b.relay(addr) {
+				relayable = append(relayable, addr)
+			}
+		}
+		addrs = relayable
+	}
+
+	if b.maxSeedAddrs > 0 {
+		addrs = b.capSeedAddrs(addrs)
+	}
+
+	// an empty book has nothing useful to send, so at least tell the peer
+	// how to reach us again
+	if b.selfAddr != nil && len(addrs) < minSelfBroadcastSelection {
+		addrs = append(addrs, b.selfAddr)
+	}
+
+	return addrs
+}
+
+// relay reports whether addr may be handed out to peers.  This is separate
+// from the book's routability rules, which decide what we store.
+func (b *seedBook) relay(addr *p2p.NetAddress) bool {
+	if b.access != nil && !b.access.Permits(addr) {
+		return false
+	}
+	if addr.OnionCatTor() {
+		return b.relayOnion
+	}
+	if !b.relayPrivate && !addrRoutable(b.reachability, addr) {
+		return false
+	}
+	return true
+}
+
+// pruneDenied removes the addresses the access list no longer permits
+func (b *seedBook) pruneDenied() {
+	for _, ka := range b.candidates() {
+		if !b.access.Permits(ka.Addr) {
+			b.logger.Info("removing address denied by the access list", "addr", ka.Addr)
+			b.RemoveAddress(ka.Addr)
+		}
+	}
+	b.invalidateSelection()
+}
+
+// addSeedID counts addresses of id as seed addresses
+func (b *seedBook) addSeedID(id p2p.ID) {
+	b.seedsMtx.Lock()
+	b.seedIDs[id] = struct{}{}
+	b.seedsMtx.Unlock()
+}
+
+// capSeedAddrs drops seed addresses beyond maxSeedAddrs from addrs
+func (b *seedBook) capSeedAddrs(addrs []*p2p.NetAddress) []*p2p.NetAddress {
+	b.seedsMtx.RLock()
+	defer b.seedsMtx.RUnlock()
+
+	capped := addrs[:0]
+	seeds := 0
+	for _, addr := range addrs {
+		if _, ok := b.seedIDs[addr.ID]; ok {
+			if seeds >= b.maxSeedAddrs {
+				continue
+			}
+			seeds++
+		}
+		capped = append(capped, addr)
+	}
+	return capped
+}
@@ -0,0 +1,103 @@
+package seed
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// portScanWindow is how far back raw connects are counted
+const portScanWindow = time.Minute
+
+// portScanDefense blocks IPs that keep opening connections to the listener
+// without ever completing a handshake
+type portScanDefense struct {
+	listenPort    uint16
+	threshold     int
+	blockDuration time.Duration
+	logger        log.Logger
+
+	mtx      sync.Mutex
+	connects *ipWindow
+	blocked  map[string]time.Time
+	swept    time.Time
+}
+
+// newPortScanDefense watches inbound connections to listenPort
+func newPortScanDefense(listenPort uint16, threshold int, blockDuration time.Duration, logger log.Logger) (*portScanDefense, error) {
+	if threshold <= 0 {
+		return nil, fmt.Errorf("port_scan_threshold must be positive, got %d", threshold)
+	}
+	return &portScanDefense{
+		listenPort:    listenPort,
+		threshold:     threshold,
+		blockDuration: blockDuration,
+		logger:        logger,
+		connects:      newIPWindow(portScanWindow),
+		blocked:       make(map[string]time.Time),
+	}, nil
+}
+
+// FilterConn is a p2p.ConnFilterFunc recording every raw inbound connect and
+// refusing blocked IPs.  The transport runs it for dialed connections too,
+// which are told apart by their local port.
+func (d *portScanDefense) FilterConn(_ p2p.ConnSet, c net.Conn, _ []net.IP) error {
+	local, ok := c.LocalAddr().(*net.TCPAddr)
+	if !ok || local.Port != int(d.listenPort) {
+		return nil
+	}
+	remote, ok := c.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+	ip := remote.IP.String()
+	now := time.Now()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if until, ok := d.blocked[ip]; ok {
+		if now.Before(until) {
+			return fmt.Errorf("%s is blocked as a suspected port scanner", ip)
+		}
+		delete(d.blocked, ip)
+	}
+
+	d.forgetExpired(now)
+
+	if connects := d.connects.Add(ip, now); connects > d.threshold {
+		d.connects.Forget(ip)
+		d.blocked[ip] = now.Add(d.blockDuration)
+		d.logger.Info("port scan detected, blocking IP",
+			"ip", ip, "connects", connects, "window", portScanWindow, "until", d.blocked[ip])
+		return fmt.Errorf("%s is blocked as a suspected port scanner", ip)
+	}
+	return nil
+}
+
+// forgetExpired drops blocks that have run out, at most once per window.
+// d.mtx must be held.
+func (d *portScanDefense) forgetExpired(now time.Time) {
+	if now.Sub(d.swept) < portScanWindow {
+		return
+	}
+	d.swept = now
+	for ip, until := range d.blocked {
+		if !now.Before(until) {
+			delete(d.blocked, ip)
+		}
+	}
+}
+
+// FilterPeer is a p2p.PeerFilterFunc.  It never rejects; reaching it means
+// the peer completed a handshake, so its IP is no longer suspicious.
+func (d *portScanDefense) FilterPeer(_ p2p.IPeerSet, peer p2p.Peer) error {
+	d.mtx.Lock()
+	d.connects.Forget(peer.RemoteIP().String())
+	d.mtx.Unlock()
+	return nil
+}
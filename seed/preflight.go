@@ -0,0 +1,145 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmnet "github.com/tendermint/tendermint/libs/net"
+	"github.com/tendermint/tendermint/version"
+)
+
+// PreflightSeverity says whether a failed preflight check stops the seed
+type PreflightSeverity int
+
+// Preflight severities
+const (
+	Warning PreflightSeverity = iota
+	Fatal
+)
+
+// String implements fmt.Stringer
+func (s PreflightSeverity) String() string {
+	if s == Fatal {
+		return "fatal"
+	}
+	return "warning"
+}
+
+// PreflightIssue is a problem found by PreflightCheck
+type PreflightIssue struct {
+	Check    string
+	Severity PreflightSeverity
+	Err      error
+}
+
+// preflightCheck is a single named startup check
+type preflightCheck struct {
+	name     string
+	severity PreflightSeverity
+	run      func() error
+}
+
+// preflightChecks lists the checks that apply to SeedConfig
+func preflightChecks(SeedConfig *Config) []preflightCheck {
+	// an existing key only needs to be readable
+	keySeverity := Fatal
+	if _, err := os.Stat(SeedConfig.NodeKeyFile); err == nil {
+		keySeverity = Warning
+	}
+
+	checks := []preflightCheck{
+		{"node key directory writable", keySeverity, func() error {
+			return checkDirWritable(filepath.Dir(SeedConfig.NodeKeyFile))
+		}},
+		{"address book directory writable", Warning, func() error {
+			return checkDirWritable(filepath.Dir(SeedConfig.AddrBookFile))
+		}},
+		{"listen address available", Fatal, func() error {
+			return checkListenAddress(SeedConfig.ListenAddress)
+		}},
+		{"seed public keys consistent", Fatal, func() error {
+			return checkSeedPublicKeys(SeedConfig.SeedPublicKeys)
+		}},
+	}
+
+	if SeedConfig.ExperimentalP2PV2 {
+		checks = append(checks, preflightCheck{"p2p transport available", Fatal, func() error {
+			return errors.New("experimental_p2p_v2 needs the transport from a newer Tendermint; this build is linked against " + version.TMCoreSemVer)
+		}})
+	}
+
+	if t, err := resolveTelemetry(*SeedConfig); err == nil && t.AccessLog {
+		checks = append(checks, preflightCheck{"access log directory writable", Fatal, func() error {
+			return checkDirWritable(filepath.Dir(SeedConfig.AccessLogFile))
+		}})
+	}
+
+	return checks
+}
+
+// PreflightCheck verifies that the files and ports SeedConfig refers to are
+// usable before the seed starts
+func PreflightCheck(SeedConfig *Config) []PreflightIssue {
+	return runPreflight(SeedConfig, log.NewNopLogger())
+}
+
+// RunPreflight runs the preflight checks, logging the outcome of each, and
+// returns an error if any fatal issue was found
+func RunPreflight(SeedConfig *Config, logger log.Logger) error {
+	fatal := 0
+	for _, issue := range runPreflight(SeedConfig, logger) {
+		if issue.Severity == Fatal {
+			fatal++
+		}
+	}
+	if fatal > 0 {
+		return fmt.Errorf("%d fatal preflight issue(s)", fatal)
+	}
+	return nil
+}
+
+// runPreflight runs every applicable check and logs its outcome
+func runPreflight(SeedConfig *Config, logger log.Logger) []PreflightIssue {
+	var issues []PreflightIssue
+	for _, check := range preflightChecks(SeedConfig) {
+		err := check.run()
+		switch {
+		case err == nil:
+			logger.Info("preflight check passed", "check", check.name)
+			continue
+		case check.severity == Fatal:
+			logger.Error("preflight check failed", "check", check.name, "severity", check.severity, "err", err)
+		default:
+			logger.Info("preflight check failed", "check", check.name, "severity", check.severity, "err", err)
+		}
+		issues = append(issues, PreflightIssue{Check: check.name, Severity: check.severity, Err: err})
+	}
+	return issues
+}
+
+// checkDirWritable creates dir if needed and makes sure we can write to it
+func checkDirWritable(dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tinyseed-preflight-")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkListenAddress makes sure nothing else is listening on laddr
+func checkListenAddress(laddr string) error {
+	protocol, address := tmnet.ProtocolAndAddress(laddr)
+	l, err := net.Listen(protocol, address)
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
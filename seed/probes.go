@@ -0,0 +1,81 @@
+package seed
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// probeChecks decide whether the seed is healthy and ready, for the
+// kubernetes liveness and readiness probes
+type probeChecks struct {
+	nodes []*seedNode
+
+	// minAddrs is how many addresses each book needs before we are ready
+	minAddrs int
+
+	// pexWindow is how long we may go without PEX activity before we are
+	// unhealthy (0 disables the check)
+	pexWindow time.Duration
+}
+
+// healthy returns why the seed is unhealthy, nothing if it is not
+func (c probeChecks) healthy() []string {
+	var problems []string
+	for _, node := range c.nodes {
+		if !node.sw.IsRunning() {
+			problems = append(problems, fmt.Sprintf("%s: switch stopped", node.chainID))
+			continue
+		}
+		if c.pexWindow > 0 {
+			if idle := time.Since(node.book.LastPEXActivity()); idle > c.pexWindow {
+				problems = append(problems, fmt.Sprintf("%s: no PEX activity for %s", node.chainID, idle.Round(time.Second)))
+			}
+		}
+	}
+	return problems
+}
+
+// ready returns why the seed is not ready, nothing if it is.  A running
+// switch means the transport is listening.
+func (c probeChecks) ready() []string {
+	var problems []string
+	for _, node := range c.nodes {
+		if !node.sw.IsRunning() {
+			problems = append(problems, fmt.Sprintf("%s: not listening", node.chainID))
+			continue
+		}
+		if size := node.book.Size(); size < c.minAddrs {
+			problems = append(problems, fmt.Sprintf("%s: %d of %d addresses", node.chainID, size, c.minAddrs))
+		}
+	}
+	return problems
+}
+
+// StartProbeServer serves GET /healthz and GET /readyz on addr.  Both
+// answer 200 or 503 with one line per problem.
+func StartProbeServer(addr string, checks probeChecks) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", probeHandler(checks.healthy))
+	mux.HandleFunc("/readyz", probeHandler(checks.ready))
+	return serveHTTP(addr, mux)
+}
+
+func probeHandler(check func() []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if problems := check(); len(problems) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, strings.Join(problems, "\n"))
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}
+}
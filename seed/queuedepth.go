@@ -0,0 +1,88 @@
+package seed
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// queueDepthReactor caps the MConn send queue of every channel a reactor
+// registers and makes the reactor's sends non-blocking.  MConn does not let
+// us evict queued messages, so when a queue is full the message being sent
+// is the one dropped.
+type queueDepthReactor struct {
+	p2p.Reactor
+
+	depth   int
+	dropped *prometheus.CounterVec
+}
+
+// newQueueDepthReactor wraps reactor so that its channels queue at most depth
+// outbound messages per peer.  A depth of 0 keeps the tendermint defaults.
+func newQueueDepthReactor(reactor p2p.Reactor, depth int, chainID string) (*queueDepthReactor, error) {
+	if depth < 0 {
+		return nil, fmt.Errorf("max_message_queue_depth must not be negative, got %d", depth)
+	}
+	return &queueDepthReactor{
+		Reactor: reactor,
+		depth:   depth,
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "messages",
+			Name:        "dropped_total",
+			Help:        "Number of outbound messages dropped because the peer's send queue was full.",
+			ConstLabels: prometheus.Labels{"chain_id": chainID},
+		}, []string{"channel"}),
+	}, nil
+}
+
+// RegisterMetrics exports tinyseed_messages_dropped_total to reg
+func (r *queueDepthReactor) RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(r.dropped)
+}
+
+// GetChannels implements p2p.Reactor
+func (r *queueDepthReactor) GetChannels() []*conn.ChannelDescriptor {
+	chDescs := r.Reactor.GetChannels()
+	if r.depth > 0 {
+		for _, chDesc := range chDescs {
+			chDesc.SendQueueCapacity = r.depth
+		}
+	}
+	return chDescs
+}
+
+// AddPeer implements p2p.Reactor
+func (r *queueDepthReactor) AddPeer(peer p2p.Peer) {
+	r.Reactor.AddPeer(r.wrap(peer))
+}
+
+// Receive implements p2p.Reactor
+func (r *queueDepthReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
+	r.Reactor.Receive(chID, r.wrap(src), msgBytes)
+}
+
+func (r *queueDepthReactor) wrap(peer p2p.Peer) p2p.Peer {
+	if r.depth == 0 {
+		return peer
+	}
+	return droppingPeer{Peer: peer, dropped: r.dropped}
+}
+
+// droppingPeer turns blocking sends into TrySend, counting what is dropped
+type droppingPeer struct {
+	p2p.Peer
+
+	dropped *prometheus.CounterVec
+}
+
+// Send implements p2p.Peer
+func (p droppingPeer) Send(chID byte, msgBytes []byte) bool {
+	if p.Peer.TrySend(chID, msgBytes) {
+		return true
+	}
+	p.dropped.WithLabelValues(fmt.Sprintf("%#x", chID)).Inc()
+	return false
+}
@@ -0,0 +1,39 @@
+package seed
+
+import (
+	"fmt"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// Network reachability modes accepted by Config.NetworkReachabilityMode
+const (
+	ReachabilityAuto    = "auto"
+	ReachabilityPublic  = "public"
+	ReachabilityPrivate = "private"
+)
+
+// RoutabilityStrict reports whether the address book should enforce strict
+// routability rules once NetworkReachabilityMode has been applied
+func (c Config) RoutabilityStrict() (bool, error) {
+	switch c.NetworkReachabilityMode {
+	case "", ReachabilityAuto:
+		return c.AddrBookStrict, nil
+	case ReachabilityPublic, ReachabilityPrivate:
+		// tendermint only knows strict or not; both modes need the book to
+		// stop rejecting RFC-1918 addresses
+		return false, nil
+	default:
+		return false, fmt.Errorf("unknown network reachability mode %q", c.NetworkReachabilityMode)
+	}
+}
+
+// addrRoutable reports whether addr is publicly routable under the given
+// network reachability mode
+func addrRoutable(mode string, addr *p2p.NetAddress) bool {
+	if mode == ReachabilityPublic {
+		// private ranges are globally unique on the networks this is for
+		return addr.Valid() == nil
+	}
+	return addr.Routable()
+}
@@ -0,0 +1,150 @@
+package seed
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmnet "github.com/tendermint/tendermint/libs/net"
+)
+
+// remoteSignerTimeout bounds every round trip to the remote signer
+const remoteSignerTimeout = 5 * time.Second
+
+// RemoteSignerRequest is a single newline-delimited JSON request sent to the
+// remote signer.  Method is either "pub_key" or "sign".
+type RemoteSignerRequest struct {
+	Method string `json:"method"`
+	Msg    []byte `json:"msg,omitempty"`
+}
+
+// RemoteSignerResponse is the newline-delimited JSON reply to a
+// RemoteSignerRequest
+type RemoteSignerResponse struct {
+	PubKey    []byte `json:"pub_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RemoteSigner is an ed25519 node key whose private half lives in an HSM or
+// remote signer.  Every signature is an RPC to the signer; the private key
+// never leaves it.
+type RemoteSigner struct {
+	protocol string
+	address  string
+	pubKey   ed25519.PubKey
+
+	mtx  sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+var _ crypto.PrivKey = (*RemoteSigner)(nil)
+
+// NewRemoteSigner connects to the signer at addr (e.g. tcp://hsm:2345) and
+// fetches its public key
+func NewRemoteSigner(addr string) (*RemoteSigner, error) {
+	protocol, address := tmnet.ProtocolAndAddress(addr)
+	s := &RemoteSigner{protocol: protocol, address: address}
+
+	resp, err := s.call(RemoteSignerRequest{Method: "pub_key"})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.PubKey) != ed25519.PubKeySize {
+		return nil, fmt.Errorf("remote signer returned a %d byte public key, expected %d", len(resp.PubKey), ed25519.PubKeySize)
+	}
+	s.pubKey = ed25519.PubKey(resp.PubKey)
+	return s, nil
+}
+
+// Bytes implements crypto.PrivKey.  The private key is not available
+// locally, so this returns nil.
+func (s *RemoteSigner) Bytes() []byte {
+	return nil
+}
+
+// Sign implements crypto.PrivKey by asking the remote signer
+func (s *RemoteSigner) Sign(msg []byte) ([]byte, error) {
+	resp, err := s.call(RemoteSignerRequest{Method: "sign", Msg: msg})
+	if err != nil {
+		return nil, err
+	}
+	if !s.pubKey.VerifySignature(msg, resp.Signature) {
+		return nil, errors.New("remote signer returned an invalid signature")
+	}
+	return resp.Signature, nil
+}
+
+// PubKey implements crypto.PrivKey
+func (s *RemoteSigner) PubKey() crypto.PubKey {
+	return s.pubKey
+}
+
+// Equals implements crypto.PrivKey
+func (s *RemoteSigner) Equals(other crypto.PrivKey) bool {
+	o, ok := other.(*RemoteSigner)
+	return ok && o.pubKey.Equals(s.pubKey)
+}
+
+// Type implements crypto.PrivKey
+func (s *RemoteSigner) Type() string {
+	return ed25519.KeyType
+}
+
+// call sends req and waits for the response, reconnecting once if the
+// existing connection has gone away
+func (s *RemoteSigner) call(req RemoteSignerRequest) (*RemoteSignerResponse, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	resp, err := s.roundTrip(req)
+	if err != nil && s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		resp, err = s.roundTrip(req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("remote signer %s: %w", s.address, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("remote signer %s: %s", s.address, resp.Error)
+	}
+	return resp, nil
+}
+
+// roundTrip performs a single request on the current connection.  The
+// caller must hold mtx.
+func (s *RemoteSigner) roundTrip(req RemoteSignerRequest) (*RemoteSignerResponse, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.protocol, s.address, remoteSignerTimeout)
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+		s.rd = bufio.NewReader(conn)
+	}
+
+	if err := s.conn.SetDeadline(time.Now().Add(remoteSignerTimeout)); err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(s.conn).Encode(req); err != nil {
+		return nil, err
+	}
+
+	line, err := s.rd.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	resp := &RemoteSignerResponse{}
+	if err := json.Unmarshal(line, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,99 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// replayTimeout bounds each call to the admin API
+const replayTimeout = 10 * time.Second
+
+// ReplaySummary counts the outcomes of replay-addresses.  Errors holds why
+// each rejected or failed address did not make it.
+type ReplaySummary struct {
+	Added    int
+	Skipped  int
+	Rejected int
+	Failed   int
+	Errors   []error
+}
+
+// ReplayAddresses posts each of addrs to url, parallel at a time.  Addresses
+// that do not parse are rejected without asking the seed.
+func ReplayAddresses(url string, addrs []string, parallel int) ReplaySummary {
+	client := &http.Client{Timeout: replayTimeout}
+
+	var mtx sync.Mutex
+	var summary ReplaySummary
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range work {
+				result, reason, err := replayAddress(client, url, addr)
+				mtx.Lock()
+				switch {
+				case err != nil:
+					summary.Errors = append(summary.Errors, fmt.Errorf("%s: %w", addr, err))
+					summary.Failed++
+				case result == AddResultAdded:
+					summary.Added++
+				case result == AddResultSkipped:
+					summary.Skipped++
+				default:
+					if reason != nil {
+						summary.Errors = append(summary.Errors, fmt.Errorf("%s: %w", addr, reason))
+					}
+					summary.Rejected++
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+
+	for _, addr := range addrs {
+		work <- addr
+	}
+	close(work)
+	wg.Wait()
+
+	return summary
+}
+
+// replayAddress adds a single address and returns the seed's verdict along
+// with the reason it gave, if any.  err is set when the seed could not be
+// asked.
+func replayAddress(client *http.Client, url, addr string) (result string, reason, err error) {
+	if _, err := p2p.NewNetAddressString(addr); err != nil {
+		return AddResultRejected, err, nil
+	}
+
+	body, err := json.Marshal(AddAddressRequest{Address: addr})
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	var reply AddAddressResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	if reply.Error != "" {
+		reason = errors.New(reply.Error)
+	}
+	return reply.Result, reason, nil
+}
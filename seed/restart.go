@@ -0,0 +1,79 @@
+package seed
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/tempfile"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// RestartState is what a seed shutting down hands over to the next run
+// through GracefulRestartFile
+type RestartState struct {
+	SavedAt time.Time `json:"saved_at"`
+
+	// Peers were connected at shutdown and are redialed on restart
+	Peers []*p2p.NetAddress `json:"peers"`
+
+	// Addresses is the address book at shutdown
+	Addresses []*p2p.NetAddress `json:"addresses"`
+
+	Stats RestartStats `json:"stats"`
+}
+
+// RestartStats are runtime stats carried over a restart
+type RestartStats struct {
+	StartedAt time.Time `json:"started_at"`
+	Restarts  int       `json:"restarts"`
+}
+
+// captureRestartState records the switch's peers and the book's addresses
+func captureRestartState(sw *p2p.Switch, book *seedBook, stats RestartStats) *RestartState {
+	state := &RestartState{
+		SavedAt:   time.Now(),
+		Addresses: book.Addresses(),
+		Stats:     stats,
+	}
+	for _, peer := range sw.Peers().List() {
+		// the node info has the address the peer listens on, which for
+		// inbound peers is not the address they connected from
+		addr, err := peer.NodeInfo().NetAddress()
+		if err != nil {
+			continue
+		}
+		state.Peers = append(state.Peers, addr)
+	}
+	return state
+}
+
+// WriteRestartState writes state to path
+func WriteRestartState(path string, state *RestartState) error {
+	bz, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	return tempfile.WriteFileAtomic(path, bz, 0600)
+}
+
+// LoadRestartState reads the state left at path by the previous run.  A
+// missing file, or one saved more than maxAge ago, yields nil.
+func LoadRestartState(path string, maxAge time.Duration) (*RestartState, error) {
+	bz, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &RestartState{}
+	if err := json.Unmarshal(bz, state); err != nil {
+		return nil, err
+	}
+	if time.Since(state.SavedAt) > maxAge {
+		return nil, nil
+	}
+	return state, nil
+}
@@ -0,0 +1,111 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	tmrand "github.com/tendermint/tendermint/libs/rand"
+)
+
+// Peer sampling algorithms accepted by Config.PeerSamplingAlgorithm
+const (
+	SamplingUniform       = "uniform"
+	SamplingBiasedRecent  = "biased-recent"
+	SamplingBiasedSuccess = "biased-success"
+)
+
+// SamplingStrategy picks the addresses served in a PEX response
+type SamplingStrategy interface {
+	Sample(candidates []KnownAddress, n int) []KnownAddress
+}
+
+// NewSamplingStrategy returns the SamplingStrategy registered under name
+func NewSamplingStrategy(name string) (SamplingStrategy, error) {
+	switch name {
+	case "", SamplingUniform:
+		return uniformSampling{}, nil
+	case SamplingBiasedRecent:
+		return weightedSampling{weight: recencyWeight}, nil
+	case SamplingBiasedSuccess:
+		return weightedSampling{weight: successWeight}, nil
+	default:
+		return nil, fmt.Errorf("unknown peer sampling algorithm %q", name)
+	}
+}
+
+// uniformSampling picks every candidate with equal probability
+type uniformSampling struct{}
+
+// Sample implements SamplingStrategy
+func (uniformSampling) Sample(candidates []KnownAddress, n int) []KnownAddress {
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	sample := make([]KnownAddress, 0, n)
+	for _, i := range tmrand.Perm(len(candidates))[:n] {
+		sample = append(sample, candidates[i])
+	}
+	return sample
+}
+
+// weightedSampling picks candidates without replacement with a probability
+// proportional to weight (Efraimidis-Spirakis)
+type weightedSampling struct {
+	weight func(ka KnownAddress) float64
+}
+
+// Sample implements SamplingStrategy
+func (s weightedSampling) Sample(candidates []KnownAddress, n int) []KnownAddress {
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+
+	type keyed struct {
+		key float64
+		ka  KnownAddress
+	}
+	keys := make([]keyed, 0, len(candidates))
+	for _, ka := range candidates {
+		w := s.weight(ka)
+		if w <= 0 {
+			w = math.SmallestNonzeroFloat64
+		}
+		keys = append(keys, keyed{key: math.Pow(tmrand.Float64(), 1/w), ka: ka})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].key > keys[j].key })
+
+	sample := make([]KnownAddress, 0, n)
+	for _, k := range keys[:n] {
+		sample = append(sample, k.ka)
+	}
+	return sample
+}
+
+// recencyWeight favours addresses we heard about recently
+func recencyWeight(ka KnownAddress) float64 {
+	return 1 / (1 + time.Since(ka.LastSeen).Hours())
+}
+
+// successWeight favours addresses we managed to dial
+func successWeight(ka KnownAddress) float64 {
+	return ka.SuccessRate()
+}
+
+// PeerScore rates an address by how reliably we could dial it, discounted by
+// how long ago we last heard of it
+func PeerScore(ka KnownAddress) float64 {
+	return successWeight(ka) * recencyWeight(ka)
+}
+
+// topScored returns the n candidates with the highest PeerScore
+func topScored(candidates []KnownAddress, n int) []KnownAddress {
+	if n >= len(candidates) {
+		return candidates
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return PeerScore(candidates[i]) > PeerScore(candidates[j])
+	})
+	return candidates[:n]
+}
@@ -0,0 +1,976 @@
+package seed
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"os"
+	"time"
+
+	"github.com/tendermint/tendermint/config"
+	tmflags "github.com/tendermint/tendermint/libs/cli/flags"
+	"github.com/tendermint/tendermint/libs/log"
+	tmstrings "github.com/tendermint/tendermint/libs/strings"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+	"github.com/tendermint/tendermint/version"
+)
+
+// Config defines the configuration format for TinySeed
+type Config struct {
+	ConfigVersion string `toml:"config_version" comment:"config schema version, used to detect outdated config files"`
+
+	ListenAddress       string `toml:"laddr" comment:"Address to listen for incoming connections\n Wildcards sharing a port may be combined for dual-stack, e.g. \"tcp://0.0.0.0:36656,tcp://[::]:36656\""`
+	ChainID             string `toml:"chain_id" comment:"network identifier (todo move to cli flag argument? keeps the config network agnostic)"`
+	NodeKeyFile         string `toml:"node_key_file" comment:"path to node_key (relative to tendermint-seed home directory or an absolute path)"`
+	AddrBookFile        string `toml:"addr_book_file" comment:"path to address book (relative to tendermint-seed home directory or an absolute path)"`
+	AddrBookStrict      bool   `toml:"addr_book_strict" comment:"Set true for strict routability rules\n Set false for private or local networks"`
+	MaxNumInboundPeers  int    `toml:"max_num_inbound_peers" comment:"maximum number of inbound connections"`
+	MaxNumOutboundPeers int    `toml:"max_num_outbound_peers" comment:"maximum number of outbound connections"`
+	Seeds               string `toml:"seeds" comment:"seed nodes we can use to discover peers"`
+
+	ExternalAddress       string `toml:"external_address" comment:"Publicly reachable address of this seed (defaults to laddr)"`
+	SeedModeBroadcastSelf bool   `toml:"seed_mode_broadcast_self" comment:"Include our own address in PEX responses when the address book has little to offer\n Useful during chain launches"`
+	PeerSamplingAlgorithm string `toml:"peer_sampling_algorithm" comment:"How addresses are picked for PEX responses: \"uniform\", \"biased-recent\" or \"biased-success\""`
+
+	TelemetryLevel       string `toml:"telemetry_level" comment:"What telemetry is collected: \"none\", \"minimal\" (metrics and access log) or \"full\" (detailed access log)\n OTEL tracing is not implemented, so no level exports traces. The settings below override the level when set"`
+	Prometheus           *bool  `toml:"prometheus" comment:"Serve prometheus metrics"`
+	PrometheusListenAddr string `toml:"prometheus_listen_addr" comment:"Address to serve prometheus metrics on"`
+	AccessLog            *bool  `toml:"access_log" comment:"Record peer connections and disconnections"`
+	DetailedAccessLog    *bool  `toml:"detailed_access_log" comment:"Include node info and connection duration in the access log"`
+	AccessLogFile        string `toml:"access_log_file" comment:"path to the access log (relative to tendermint-seed home directory or an absolute path)"`
+
+	NetworkReachabilityMode string `toml:"network_reachability_mode" comment:"How address routability is judged: \"auto\" (follow addr_book_strict), \"public\" (every address is routable, e.g. on a VPN)\n or \"private\" (accept RFC-1918 addresses)"`
+
+	WarmUpPeriod time.Duration `toml:"warm_up_period" comment:"How long after startup the crawler keeps unreachable addresses, so a fresh address book can grow"`
+
+	ChainIDHashPrefix bool `toml:"chain_id_hash_prefix" comment:"Non-standard: mix sha256(chain_id) into the node key so the node ID is namespaced per chain\n Every peer that needs to predict our node ID must use the same convention"`
+
+	MaxSeedAddressesInPEX int `toml:"max_seed_addresses_in_pex" comment:"Maximum number of addresses from seeds included in a single PEX response (0 for no limit)"`
+
+	ChannelPriorityMap ChannelPriorities `toml:"channel_priority_map" comment:"MConn send priority per channel ID, e.g. { 0 = 10 } to let PEX traffic preempt everything else\n Applies to the PEX channel (0x00) and, with mempool_channel_support, the mempool channel (0x30)"`
+
+	ExportAddrBookOnShutdown bool   `toml:"export_addr_book_on_shutdown" comment:"Write every known peer as id@host:port when shutting down"`
+	ExportOnShutdownFile     string `toml:"export_on_shutdown_file" comment:"Where to write the shutdown peer list (empty for stdout)"`
+
+	PEXRelayOnionAddresses   bool `toml:"pex_relay_onion_addresses" comment:"Hand out onion addresses in PEX responses"`
+	PEXRelayPrivateAddresses bool `toml:"pex_relay_private_addresses" comment:"Hand out private and other non-routable addresses in PEX responses\n Unlike addr_book_strict, this only affects what we relay, not what we store"`
+
+	RemoteSignerAddress string `toml:"remote_signer_address" comment:"Sign with a node key held by a remote signer or HSM, e.g. \"tcp://hsm.example.com:2345\"\n node_key_file is ignored when set"`
+
+	ConcurrentAddressBookWrites bool `toml:"concurrent_address_book_writes" comment:"Apply address book insertions from a pool of workers instead of the calling goroutine"`
+	AddressBookWriteWorkers     int  `toml:"address_book_write_workers" comment:"Number of address book write workers, at most one per address book shard"`
+
+	AdaptiveMaxPeers          bool   `toml:"adaptive_max_peers" comment:"Lower max_num_inbound_peers while the heap is close to adaptive_max_peers_heap_limit"`
+	AdaptiveMaxPeersHeapLimit uint64 `toml:"adaptive_max_peers_heap_limit" comment:"Heap size in bytes the adaptive inbound peer limit works against"`
+
+	KeyManagerPlugin string `toml:"key_manager_plugin" comment:"Where the node key comes from: \"file\" (node_key_file), \"env\" (TINYSEED_NODE_KEY), \"secret\"\n (node_key_secret_file), \"vault\" or the path to a Go plugin exporting a seed.KeyManager\n as the symbol \"KeyManager\", built against the same tinyseed version"`
+	VaultKeyPath     string `toml:"vault_key_path" comment:"Vault secret holding the node key in its priv_key field, e.g. \"secret/data/tinyseed\""`
+
+	MaxResponseLatencyBudget time.Duration `toml:"max_response_latency_budget" comment:"Ask every outbound peer for addresses when it connects, and disconnect and temporarily reject those that take longer than this to answer (0 to disable)"`
+
+	AddrBookTargetSize    int        `toml:"addr_book_target_size" comment:"Address book size considered fully healthy by tinyseed_addrbook_health_score"`
+	AddrBookHealthWeights [3]float64 `toml:"addr_book_health_weights" comment:"Weights of recency, success rate and size in tinyseed_addrbook_health_score"`
+
+	NodeKeyRecoveryMode bool `toml:"node_key_recovery_mode" comment:"Move a corrupted node_key_file aside and generate a new identity instead of failing to start"`
+
+	PortScanDefense       bool          `toml:"port_scan_defense" comment:"Block IPs that repeatedly connect without completing a handshake"`
+	PortScanThreshold     int           `toml:"port_scan_threshold" comment:"Raw connects within a minute, without a successful handshake, after which an IP is blocked"`
+	PortScanBlockDuration time.Duration `toml:"port_scan_block_duration" comment:"How long a suspected port scanner stays blocked"`
+
+	UDPDiscovery        bool   `toml:"udp_discovery" comment:"Find other seeds on the local network by UDP multicast or broadcast\n Ignored when addr_book_strict is true"`
+	UDPDiscoveryPort    int    `toml:"udp_discovery_port" comment:"UDP port discovery announcements are sent to and received on"`
+	UDPDiscoveryAddress string `toml:"udp_discovery_address" comment:"Multicast group or broadcast address for discovery announcements"`
+
+	AddressShuffleOnExport bool `toml:"address_shuffle_on_export" comment:"Export addresses in random order so the output does not reveal the book's internal layout"`
+
+	PeerHandshakeRateLimit float64       `toml:"peer_handshake_rate_limit" comment:"Maximum handshakes started per second (0 for no limit)"`
+	HandshakeQueueTimeout  time.Duration `toml:"handshake_queue_timeout" comment:"How long a connection may wait for its handshake before it is closed"`
+
+	LogOutput string `toml:"log_output" comment:"Where logs go: \"stdout\", \"stderr\" or an absolute file path (reopened on SIGHUP)\n The access log is written separately"`
+
+	MaxMessageQueueDepth int `toml:"max_message_queue_depth" comment:"Maximum outbound messages queued per peer and channel; further messages are dropped (0 for the tendermint default)"`
+
+	PeerScoreEnabled bool `toml:"peer_score_enabled" comment:"Score known addresses by dial success and recency"`
+	PeerGossipTopN   int  `toml:"peer_gossip_top_n" comment:"Only hand out addresses among the N best scored ones (0 for no restriction)\n Requires peer_score_enabled"`
+
+	AddressBookIntegrityCheck bool `toml:"address_book_integrity_check" comment:"Verify the address book against its sha256 sidecar at startup, restoring the backup or starting empty if it is corrupted"`
+
+	PeerListCacheTTL        time.Duration `toml:"peer_list_cache_ttl" comment:"Serve the last computed PEX response for up to this long (0 to disable)"`
+	PeerListCacheMaxChanges int           `toml:"peer_list_cache_max_changes" comment:"Recompute the cached PEX response early once this many addresses were added to or removed from the book (1 to recompute on every change)"`
+
+	AddrBookShardCount int `toml:"addr_book_shard_count" comment:"Split the address book into this many shards; each PEX request is answered from the requester's shard"`
+
+	MempoolChannelSupport bool `toml:"mempool_channel_support" comment:"Advertise the mempool channel for nodes that require it; transactions received on it are discarded"`
+
+	SeedPublicKeys map[string]string `toml:"seed_public_keys" comment:"Node ID each seed must present, e.g. { \"id@host:port\" = \"id\" }\n The seed refuses to start if a pinned ID differs from the one in its address; seeds presenting another ID are refused when dialed"`
+
+	GeoIPDatabase                string `toml:"geoip_database" comment:"Path to a MaxMind GeoLite2 City database, used to tag addresses with their country and continent\n in the peers API and metrics"`
+	AddrBookGeographicPreference string `toml:"addr_book_geographic_preference" comment:"How PEX responses use geography: \"none\", \"near\" (peers close to this seed), \"requester\" (peers close\n to whoever asks) or \"diverse\" (as many continents and countries as possible). Requires geoip_database"`
+
+	GracefulRestartFile       string        `toml:"graceful_restart_file" comment:"On shutdown, write connected peers, the address book and runtime stats here for the next\n run to pick up. Empty disables it"`
+	GracefulRestartFileMaxAge time.Duration `toml:"graceful_restart_file_max_age" comment:"Ignore a graceful_restart_file older than this"`
+
+	AdminListenAddress string `toml:"admin_listen_address" comment:"Address to serve the admin API on, e.g. \"127.0.0.1:26680\" (empty disables it)\n The API is unauthenticated, so keep it off public interfaces"`
+
+	ExperimentalP2PV2 bool `toml:"experimental_p2p_v2" comment:"Reserved for Tendermint's multiplexed-stream transport. This build only has the v1 MultiplexTransport\n and refuses to start when this is set"`
+
+	CryptoAuditLog string `toml:"crypto_audit_log" comment:"JSONL file recording every handshake with its outcome and the node IDs involved (empty disables it)"`
+
+	PeersListenAddress string `toml:"peers_listen_addr" comment:"Address to serve the address book as JSON on, at GET /peers (empty disables it)"`
+
+	Chains []ChainConfig `toml:"chains" comment:"Serve several networks from this process, one [[chains]] table each\n Every chain shares the settings above; its files go into a directory named after its chain_id"`
+
+	LogLevel  string `toml:"log_level" comment:"Log level, optionally per module, e.g. \"info\" or \"pex:debug,book:info,switch:error,*:info\""`
+	LogFormat string `toml:"log_format" comment:"Log format: \"plain\" or \"json\""`
+
+	Crawler               bool          `toml:"crawler" comment:"Probe addresses from the address book in the background, evict the ones that keep failing\n and prefer recently verified ones in PEX responses"`
+	CrawlerInterval       time.Duration `toml:"crawler_interval" comment:"How often a batch of addresses is probed"`
+	CrawlerBatchSize      int           `toml:"crawler_batch_size" comment:"Addresses probed per round, least recently probed first"`
+	CrawlerMaxFailures    int           `toml:"crawler_max_failures" comment:"Consecutive failed probes after which an address is evicted"`
+	CrawlerVerifiedWindow time.Duration `toml:"crawler_verified_window" comment:"How recently an address must have answered a probe to be preferred in PEX responses"`
+
+	AccessListFile string `toml:"access_list_file" comment:"TOML file with allow = [...] and deny = [...] lists of node IDs, CIDR ranges and IPs (empty disables it)\n Applies to connections and to which addresses are stored and gossiped; reloaded on SIGHUP"`
+
+	HealthListenAddress   string        `toml:"health_listen_addr" comment:"Address to serve kubernetes probes on, at GET /healthz and GET /readyz (empty disables it)"`
+	ReadinessMinAddresses int           `toml:"readiness_min_addresses" comment:"Addresses every chain's address book needs before /readyz reports ready"`
+	HealthPEXWindow       time.Duration `toml:"health_pex_window" comment:"/healthz fails when no PEX request was answered and no address received for this long (0 disables the check)"`
+
+	InboundConnsPerIPPerMinute int           `toml:"inbound_conns_per_ip_per_minute" comment:"New inbound connections accepted from one IP per minute, beyond which they are refused (0 for no limit)"`
+	MaxConcurrentHandshakes    int           `toml:"max_concurrent_handshakes" comment:"Inbound handshakes in flight at once, beyond which new connections are refused (0 for no limit)"`
+	InboundHandshakeTimeout    time.Duration `toml:"inbound_handshake_timeout" comment:"How long an inbound connection may take to complete its handshake and be accepted, at most tendermint's\n own 3s (0 keeps 3s)"`
+
+	P2PVersion   uint64 `toml:"p2p_version" comment:"P2P protocol version advertised to peers (0 uses the one tinyseed is built with)"`
+	BlockVersion uint64 `toml:"block_version" comment:"Block protocol version advertised to peers, which must match the chain's (0 uses the one tinyseed is built with)"`
+
+	AddrBookSaveInterval time.Duration `toml:"addr_book_save_interval" comment:"How often the address book is saved while running, besides at shutdown (0 only saves at shutdown)"`
+	AddrBookBackups      int           `toml:"addr_book_backups" comment:"Timestamped backups of the address book kept next to it, taken on every save and restored from\n when address_book_integrity_check finds the book corrupted (0 keeps none)"`
+
+	SeedsURL                string        `toml:"seeds_url" comment:"URL of a cosmos/chain-registry chain.json or of a plain text list of id@host:port seeds, fetched at\n startup and merged with seeds (empty disables it)"`
+	SeedsURLRefreshInterval time.Duration `toml:"seeds_url_refresh_interval" comment:"How often seeds_url is fetched again, dialing the seeds that are new (0 only fetches it at startup)"`
+
+	NodeKeySecretFile string `toml:"node_key_secret_file" comment:"Read-only file holding a base64 encoded ed25519 private key or 32 byte seed, or a node_key.json, e.g. a mounted\n kubernetes secret. Used when key_manager_plugin is \"secret\""`
+
+	WebUIListenAddress string `toml:"web_ui_listen_addr" comment:"Address to serve a status page on, showing connected peers, address book size over time,\n PEX activity and the seed's id@host:port (empty disables it). Read-only, but it lists every peer"`
+
+	DNSSeedListenAddress  string        `toml:"dns_seed_listen_addr" comment:"UDP address to answer DNS queries on, e.g. \":53\" (empty disables it). A and AAAA queries get\n verified peers, TXT queries their id@host:port"`
+	DNSSeedZone           string        `toml:"dns_seed_zone" comment:"Zone delegated to this seed with an NS record, e.g. \"seed.example.com\"\n With [[chains]] every chain is answered at <chain_id>.<zone>"`
+	DNSSeedTTL            time.Duration `toml:"dns_seed_ttl" comment:"TTL of the records the DNS seed hands out"`
+	DNSSeedPort           int           `toml:"dns_seed_port" comment:"A and AAAA records cannot carry a port, so they only list peers listening on this one"`
+	DNSSeedVerifiedWindow time.Duration `toml:"dns_seed_verified_window" comment:"Only peers the seed or the crawler reached this recently are handed out"`
+
+	PeerHistoryFile string `toml:"peer_history_file" comment:"bbolt database recording every node's first and last sighting, dials, handshakes and source address\n across restarts, e.g. \"data/peer_history.db\" (relative to the home directory; empty disables it)"`
+
+	// EventHooks can only be set by programs embedding the seed
+	EventHooks EventHooks `toml:"-"`
+}
+
+// DefaultConfig returns a seed config initialized with default values
+func DefaultConfig(homeDir string) *Config {
+	return &Config{
+		ConfigVersion: CurrentConfigVersion,
+
+		ListenAddress:       "tcp://0.0.0.0:36656",
+		ChainID:             "columbus-5",
+		NodeKeyFile:         filepath.Join(homeDir, "config/node_key.json"),
+		AddrBookFile:        filepath.Join(homeDir, "data/addrbook.json"),
+		AddrBookStrict:      true,
+		MaxNumInboundPeers:  1000,
+		MaxNumOutboundPeers: 1000,
+		Seeds:               "e999fc20aa5b87c1acef8677cf495ad85061cfb9@seed.terra.delightlabs.io:26656,6d8e943c049a80c161a889cb5fcf3d184215023e@public-seed2.terra.dev:26656,87048bf71526fb92d73733ba3ddb79b7a83ca11e@public-seed.terra.dev:26656",
+
+		PeerSamplingAlgorithm: SamplingUniform,
+
+		TelemetryLevel:       TelemetryNone,
+		PrometheusListenAddr: ":26660",
+		AccessLogFile:        filepath.Join(homeDir, "data/access.log"),
+
+		NetworkReachabilityMode: ReachabilityAuto,
+
+		WarmUpPeriod: 15 * time.Minute,
+
+		MaxSeedAddressesInPEX: 10,
+
+		AddressBookWriteWorkers: 4,
+
+		AdaptiveMaxPeersHeapLimit: 1 << 30,
+
+		KeyManagerPlugin: "file",
+
+		MaxResponseLatencyBudget: 10 * time.Second,
+
+		AddrBookTargetSize:    1000,
+		AddrBookHealthWeights: [3]float64{0.4, 0.3, 0.3},
+
+		PortScanThreshold:     5,
+		PortScanBlockDuration: time.Hour,
+
+		UDPDiscoveryPort:    36657,
+		UDPDiscoveryAddress: "224.0.0.1",
+
+		AddressShuffleOnExport: true,
+
+		HandshakeQueueTimeout: 5 * time.Second,
+
+		LogOutput: LogOutputStdout,
+
+		AddressBookIntegrityCheck: true,
+
+		AddrBookShardCount: 1,
+
+		PeerListCacheMaxChanges: 100,
+
+		AddrBookGeographicPreference: GeoPreferenceNone,
+
+		GracefulRestartFileMaxAge: 5 * time.Minute,
+
+		LogLevel:  defaultLogLevel,
+		LogFormat: LogFormatPlain,
+
+		CrawlerInterval:       time.Minute,
+		CrawlerBatchSize:      10,
+		CrawlerMaxFailures:    5,
+		CrawlerVerifiedWindow: time.Hour,
+
+		AddrBookSaveInterval: time.Minute,
+		AddrBookBackups:      3,
+
+		SeedsURLRefreshInterval: time.Hour,
+
+		DNSSeedTTL:            time.Minute,
+		DNSSeedPort:           26656,
+		DNSSeedVerifiedWindow: 24 * time.Hour,
+
+		ReadinessMinAddresses: 1,
+		HealthPEXWindow:       15 * time.Minute,
+	}
+}
+
+// Version is the tinyseed release, also advertised in the node info
+const Version = "0.5.9"
+
+// ProtocolVersion is what the seed advertises to peers: P2PVersion and
+// BlockVersion, or the versions of the tendermint we are built with
+func (c Config) ProtocolVersion() p2p.ProtocolVersion {
+	pv := p2p.NewProtocolVersion(version.P2PProtocol, version.BlockProtocol, 0)
+	if c.P2PVersion != 0 {
+		pv.P2P = c.P2PVersion
+	}
+	if c.BlockVersion != 0 {
+		pv.Block = c.BlockVersion
+	}
+	return pv
+}
+
+// exportOnShutdown writes addrs to path, or to stdout if path is empty,
+// optionally in random order
+func exportOnShutdown(path string, addrs []*p2p.NetAddress, shuffle bool) error {
+	if shuffle {
+		var err error
+		if addrs, err = ShufflePeerList(addrs); err != nil {
+			return err
+		}
+	}
+
+	if path == "" {
+		return WritePeerList(os.Stdout, addrs)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WritePeerList(f, addrs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Seed is a Tenderseed, or one per entry of Config.Chains, along with the
+// HTTP and DNS servers the config asks for
+type Seed struct {
+	config    Config
+	logger    log.Logger
+	telemetry telemetry
+	metrics   *metricsRegistry
+	nodes     []*seedNode
+
+	mtx     sync.Mutex
+	running []*seedNode
+	servers []io.Closer
+	stopped bool
+
+	// done is closed once Stop has saved and stopped everything
+	done chan struct{}
+}
+
+// New sets up a seed for cfg.  Nothing listens until Start is called.
+func New(cfg Config) (*Seed, error) {
+	logOutput, err := OpenLogOutput(cfg.LogOutput)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := NewLogger(logOutput, cfg.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	CheckConfigVersion(cfg, logger)
+
+	telemetry, err := resolveTelemetry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TelemetryLevel == TelemetryFull {
+		logger.Info("telemetry_level full does not export OTEL traces, tracing is not implemented")
+	}
+
+	s := &Seed{config: cfg, logger: logger, telemetry: telemetry, metrics: newMetricsRegistry(), done: make(chan struct{})}
+	if len(cfg.Chains) == 0 {
+		node, err := newSeedNode(cfg, logger, s.metrics)
+		if err != nil {
+			return nil, err
+		}
+		s.nodes = append(s.nodes, node)
+	} else {
+		chains, err := cfg.ChainConfigs()
+		if err != nil {
+			return nil, err
+		}
+		for _, chainConfig := range chains {
+			node, err := newSeedNode(chainConfig, logger.With("chain", chainConfig.ChainID), s.metrics)
+			if err != nil {
+				return nil, fmt.Errorf("chain %s: %w", chainConfig.ChainID, err)
+			}
+			s.nodes = append(s.nodes, node)
+		}
+	}
+	return s, nil
+}
+
+// Start starts every chain and the HTTP and DNS servers.  If any of them fails the
+// ones already started are stopped again.
+func (s *Seed) Start() error {
+	if err := s.start(); err != nil {
+		s.Stop()
+		return err
+	}
+	return nil
+}
+
+func (s *Seed) start() error {
+	for _, node := range s.nodes {
+		if err := node.start(); err != nil {
+			return fmt.Errorf("chain %s: %w", node.chainID, err)
+		}
+		s.mtx.Lock()
+		s.running = append(s.running, node)
+		s.mtx.Unlock()
+	}
+
+	if s.telemetry.Prometheus {
+		srv, err := StartMetricsServer(s.config.PrometheusListenAddr, s.metrics)
+		if err != nil {
+			return fmt.Errorf("metrics server: %w", err)
+		}
+		s.addServer(srv)
+		s.logger.Info("serving metrics", "addr", s.config.PrometheusListenAddr)
+	}
+
+	books := make(map[string]*seedBook, len(s.nodes))
+	for _, node := range s.nodes {
+		books[node.chainID] = node.book
+	}
+
+	if s.config.PeersListenAddress != "" {
+		srv, err := StartPeersServer(s.config.PeersListenAddress, books)
+		if err != nil {
+			return fmt.Errorf("peers server: %w", err)
+		}
+		s.addServer(srv)
+		s.logger.Info("serving peers", "addr", s.config.PeersListenAddress)
+	}
+
+	if s.config.DNSSeedListenAddress != "" {
+		dns, err := StartDNSSeed(s.config.DNSSeedListenAddress, s.config.DNSSeedZone, s.config.DNSSeedTTL,
+			s.config.DNSSeedPort, s.config.DNSSeedVerifiedWindow, books, s.logger.With("module", "dns"))
+		if err != nil {
+			return fmt.Errorf("DNS seed: %w", err)
+		}
+		s.addServer(dns)
+		s.logger.Info("serving DNS seed", "addr", s.config.DNSSeedListenAddress, "zone", s.config.DNSSeedZone)
+	}
+
+	if s.config.WebUIListenAddress != "" {
+		srv, err := StartWebUIServer(s.config.WebUIListenAddress, s.nodes)
+		if err != nil {
+			return fmt.Errorf("web UI: %w", err)
+		}
+		s.addServer(srv)
+		s.logger.Info("serving web UI", "addr", s.config.WebUIListenAddress)
+	}
+
+	if s.config.HealthListenAddress != "" {
+		srv, err := StartProbeServer(s.config.HealthListenAddress, probeChecks{
+			nodes:     s.nodes,
+			minAddrs:  s.config.ReadinessMinAddresses,
+			pexWindow: s.config.HealthPEXWindow,
+		})
+		if err != nil {
+			return fmt.Errorf("health server: %w", err)
+		}
+		s.addServer(srv)
+		s.logger.Info("serving health probes", "addr", s.config.HealthListenAddress)
+	}
+	return nil
+}
+
+func (s *Seed) addServer(srv io.Closer) {
+	s.mtx.Lock()
+	s.servers = append(s.servers, srv)
+	s.mtx.Unlock()
+}
+
+// Stop shuts the HTTP and DNS servers down and stops every running chain, saving
+// its address book.  Calling it more than once is harmless.
+func (s *Seed) Stop() {
+	s.mtx.Lock()
+	if s.stopped {
+		s.mtx.Unlock()
+		return
+	}
+	s.stopped = true
+	servers, running := s.servers, s.running
+	s.mtx.Unlock()
+
+	s.logger.Info("shutting down...")
+	for _, srv := range servers {
+		srv.Close()
+	}
+
+	var wg sync.WaitGroup
+	for _, node := range running {
+		wg.Add(1)
+		go func(node *seedNode) {
+			defer wg.Done()
+			node.stop()
+		}(node)
+	}
+	wg.Wait()
+	close(s.done)
+}
+
+// Wait blocks until every chain started by Start has stopped.  Once Stop
+// was called it also waits for the address books to be saved.
+func (s *Seed) Wait() {
+	s.mtx.Lock()
+	running := s.running
+	s.mtx.Unlock()
+
+	for _, node := range running {
+		node.sw.Wait()
+	}
+
+	s.mtx.Lock()
+	stopped := s.stopped
+	s.mtx.Unlock()
+	if stopped {
+		<-s.done
+	}
+}
+
+// seedNode is the seed for a single chain
+type seedNode struct {
+	chainID  string
+	selfAddr *p2p.NetAddress
+	sw       *p2p.Switch
+	book     *seedBook
+	start    func() error
+	stop     func()
+}
+
+// newSeedNode sets up the seed for SeedConfig's chain, registering its
+// metrics with metrics
+func newSeedNode(SeedConfig Config, logger log.Logger, metrics *metricsRegistry) (*seedNode, error) {
+	laddr, err := ResolveListenAddress(SeedConfig.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	SeedConfig.ListenAddress = laddr
+
+	if err := RunPreflight(&SeedConfig, logger); err != nil {
+		logger.Error("refusing to start", "err", err)
+		return nil, fmt.Errorf("refusing to start: %w", err)
+	}
+
+	chainID := SeedConfig.ChainID
+	nodeKeyFilePath := SeedConfig.NodeKeyFile
+	addrBookFilePath := SeedConfig.AddrBookFile
+
+	if err := os.MkdirAll(filepath.Dir(nodeKeyFilePath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(addrBookFilePath), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	cfg := config.DefaultP2PConfig()
+	cfg.AllowDuplicateIP = true
+
+	// allow a lot of inbound peers since we disconnect from them quickly in seed mode
+	cfg.MaxNumInboundPeers = SeedConfig.MaxNumInboundPeers
+
+	// keep trying to make outbound connections to exchange peering info
+	cfg.MaxNumOutboundPeers = SeedConfig.MaxNumOutboundPeers
+
+	telemetry, err := resolveTelemetry(SeedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	addrBookStrict, err := SeedConfig.RoutabilityStrict()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeKey, err := LoadSeedNodeKey(SeedConfig, logger.With("module", "nodekey"))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("tenderseed",
+		"key", nodeKey.ID(),
+		"key path", nodeKeyFilePath,
+		"address book path", addrBookFilePath,
+		"listen", SeedConfig.ListenAddress,
+		"chain", chainID,
+		"strict-routing", addrBookStrict,
+		"reachability", SeedConfig.NetworkReachabilityMode,
+		"max-inbound", SeedConfig.MaxNumInboundPeers,
+		"max-outbound", SeedConfig.MaxNumOutboundPeers,
+		"broadcast-self", SeedConfig.SeedModeBroadcastSelf,
+		"sampling", SeedConfig.PeerSamplingAlgorithm,
+		"telemetry", SeedConfig.TelemetryLevel,
+	)
+
+	filteredLogger, err := tmflags.ParseLogLevel(SeedConfig.LogLevel, logger, defaultLogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	// NodeInfo gets info on your node
+	nodeInfo := p2p.DefaultNodeInfo{
+		ProtocolVersion: SeedConfig.ProtocolVersion(),
+		DefaultNodeID:   nodeKey.ID(),
+		ListenAddr:      SeedConfig.ListenAddress,
+		Network:         chainID,
+		Version:         Version,
+		Channels:        []byte{pex.PexChannel},
+		Moniker:         fmt.Sprintf("%s-seed", chainID),
+	}
+	if SeedConfig.MempoolChannelSupport {
+		nodeInfo.Channels = append(nodeInfo.Channels, MempoolChannel)
+	}
+
+	addr, err := p2p.NewNetAddressString(p2p.IDAddressString(nodeInfo.DefaultNodeID, nodeInfo.ListenAddr))
+	if err != nil {
+		return nil, err
+	}
+
+	transport := p2p.NewMultiplexTransport(nodeInfo, *nodeKey, p2p.MConnConfig(cfg))
+
+	var portScan *portScanDefense
+	if SeedConfig.PortScanDefense {
+		portScan, err = newPortScanDefense(addr.Port, SeedConfig.PortScanThreshold, SeedConfig.PortScanBlockDuration, filteredLogger.With("module", "portscan"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var access *accessListFilter
+	if SeedConfig.AccessListFile != "" {
+		access, err = newAccessListFilter(SeedConfig.AccessListFile, filteredLogger.With("module", "access"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var throttle *inboundThrottle
+	if SeedConfig.InboundConnsPerIPPerMinute > 0 || SeedConfig.MaxConcurrentHandshakes > 0 || SeedConfig.InboundHandshakeTimeout > 0 {
+		throttle, err = newInboundThrottle(addr.Port, SeedConfig.InboundConnsPerIPPerMinute, SeedConfig.MaxConcurrentHandshakes, SeedConfig.InboundHandshakeTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var limiter *handshakeLimiter
+	if SeedConfig.PeerHandshakeRateLimit > 0 {
+		limiter, err = newHandshakeLimiter(SeedConfig.PeerHandshakeRateLimit, SeedConfig.HandshakeQueueTimeout)
+		if err != nil {
+			return nil, err
+		}
+		// queued connections must not trip the transport's own filter timeout
+		p2p.MultiplexTransportFilterTimeout(SeedConfig.HandshakeQueueTimeout + time.Second)(transport)
+	}
+
+	var handshakes *handshakeTimer
+	if SeedConfig.EventHooks.OnHandshakeComplete != nil {
+		handshakes = newHandshakeTimer(SeedConfig.EventHooks.OnHandshakeComplete)
+	}
+
+	var audit *cryptoAudit
+	if SeedConfig.CryptoAuditLog != "" {
+		if err := os.MkdirAll(filepath.Dir(SeedConfig.CryptoAuditLog), os.ModePerm); err != nil {
+			return nil, err
+		}
+		audit, err = newCryptoAudit(SeedConfig.CryptoAuditLog, addr.Port, filteredLogger.With("module", "audit"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// rejecting filters first, so that the handshake limiter only spends
+	// tokens on connections that will go on to the handshake
+	var connFilters []p2p.ConnFilterFunc
+	if portScan != nil {
+		connFilters = append(connFilters, portScan.FilterConn)
+	}
+	if access != nil {
+		connFilters = append(connFilters, access.FilterConn)
+	}
+	if throttle != nil {
+		connFilters = append(connFilters, throttle.FilterConn)
+	}
+	if limiter != nil {
+		connFilters = append(connFilters, limiter.FilterConn)
+	}
+	if throttle != nil {
+		connFilters = append(connFilters, throttle.StartHandshake)
+	}
+	if handshakes != nil {
+		connFilters = append(connFilters, handshakes.FilterConn)
+	}
+	if audit != nil {
+		connFilters = append(connFilters, audit.FilterConn)
+	}
+
+	p2p.MultiplexTransportConnFilters(sequentialConnFilter(connFilters...))(transport)
+
+	if SeedConfig.AddressBookIntegrityCheck {
+		for _, path := range AddrBookFiles(SeedConfig) {
+			if err := CheckAddrBookIntegrity(path, logger.With("module", "book")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// bring older address books up to date and hand tendermint a working
+	// copy of each
+	book, err := openAddrBook(SeedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	externalAddress := SeedConfig.ExternalAddress
+	if externalAddress == "" {
+		externalAddress = SeedConfig.ListenAddress
+	}
+	selfAddr, err := p2p.NewNetAddressString(p2p.IDAddressString(nodeKey.ID(), externalAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	pexBook, err := newSeedBook(book, SeedConfig, selfAddr)
+	if err != nil {
+		return nil, err
+	}
+	pexBook.SetLogger(filteredLogger.With("module", "book"))
+	pexBook.access = access
+	if SeedConfig.PeerHistoryFile != "" {
+		pexBook.history = newPeerHistory(SeedConfig.PeerHistoryFile, filteredLogger.With("module", "history"))
+	}
+
+	var geoip *GeoIP
+	if SeedConfig.GeoIPDatabase != "" {
+		geoip, err = OpenGeoIP(SeedConfig.GeoIPDatabase)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if geoip != nil {
+		pexBook.setGeoIP(geoip)
+	}
+	pexBook.geo, err = newGeoSelector(SeedConfig.AddrBookGeographicPreference, geoip, selfAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	var seedsURL *seedsRefresher
+	if SeedConfig.SeedsURL != "" {
+		seedsURL, err = newSeedsRefresher(SeedConfig.SeedsURL, SeedConfig.SeedsURLRefreshInterval, tmstrings.SplitAndTrim(SeedConfig.Seeds, ",", " "), pexBook, filteredLogger.With("module", "seeds"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var crawl *crawler
+	if SeedConfig.Crawler {
+		crawl, err = newCrawler(pexBook, chainID, SeedConfig.ProtocolVersion(), SeedConfig.CrawlerInterval, SeedConfig.CrawlerBatchSize, SeedConfig.CrawlerMaxFailures, filteredLogger.With("module", "crawler"))
+		if err != nil {
+			return nil, err
+		}
+		pexBook.verifiedWithin = SeedConfig.CrawlerVerifiedWindow
+	}
+
+	restartStats := RestartStats{StartedAt: time.Now()}
+	var restartPeers []string
+	if SeedConfig.GracefulRestartFile != "" {
+		state, err := LoadRestartState(SeedConfig.GracefulRestartFile, SeedConfig.GracefulRestartFileMaxAge)
+		if err != nil {
+			logger.Error("failed to load graceful restart file", "file", SeedConfig.GracefulRestartFile, "err", err)
+		} else if state != nil {
+			for _, addr := range state.Addresses {
+				if err := pexBook.AddAddress(addr, addr); err != nil {
+					logger.Debug("failed to restore address", "addr", addr, "err", err)
+				}
+			}
+			for _, addr := range state.Peers {
+				restartPeers = append(restartPeers, addr.String())
+			}
+			restartStats.StartedAt = state.Stats.StartedAt
+			restartStats.Restarts = state.Stats.Restarts + 1
+			if err := os.Remove(SeedConfig.GracefulRestartFile); err != nil {
+				logger.Error("failed to remove graceful restart file", "file", SeedConfig.GracefulRestartFile, "err", err)
+			}
+			logger.Info("restored state from graceful restart file",
+				"saved", state.SavedAt, "peers", len(state.Peers), "addresses", len(state.Addresses))
+		}
+	}
+
+	pexReactor := pex.NewReactor(pexBook, &pex.ReactorConfig{
+		SeedMode: true,
+		Seeds:    tmstrings.SplitAndTrim(SeedConfig.Seeds, ",", " "),
+	})
+	pexReactor.SetLogger(filteredLogger.With("module", "pex"))
+
+	var swOpts []p2p.SwitchOption
+	var peerFilters []p2p.PeerFilterFunc
+
+	var balancedPexReactor p2p.Reactor = pexReactor
+	if SeedConfig.AddrBookShardCount > 1 || pexBook.geo.perRequester() {
+		balancedPexReactor = newBalancedPexReactor(pexReactor, pexBook)
+	}
+
+	budgetedPexReactor := balancedPexReactor
+	var latencyBudget *latencyBudgetReactor
+	if SeedConfig.MaxResponseLatencyBudget > 0 {
+		rejects := newRejectCache()
+		latencyBudget = newLatencyBudgetReactor(balancedPexReactor, SeedConfig.MaxResponseLatencyBudget, rejects, chainID, filteredLogger.With("module", "pex"))
+		budgetedPexReactor = latencyBudget
+		pexBook.askOnConnect = true
+		peerFilters = append(peerFilters, rejects.FilterPeer)
+	}
+
+	queuedPexReactor, err := newQueueDepthReactor(budgetedPexReactor, SeedConfig.MaxMessageQueueDepth, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	if telemetry.Prometheus {
+		swOpts = append(swOpts, p2p.WithMetrics(metrics.chainP2PMetrics(chainID)))
+		RegisterAddrBookMetrics(metrics, pexBook, chainID)
+		if err := RegisterHealthScoreMetric(metrics, pexBook, chainID, SeedConfig.AddrBookTargetSize, SeedConfig.AddrBookHealthWeights); err != nil {
+			return nil, err
+		}
+		if latencyBudget != nil {
+			latencyBudget.RegisterMetrics(metrics)
+		}
+		queuedPexReactor.RegisterMetrics(metrics)
+		pexBook.metrics = newSeedMetrics(chainID)
+		pexBook.metrics.RegisterMetrics(metrics)
+		if geoip != nil {
+			RegisterGeoMetrics(metrics, pexBook, chainID)
+		}
+	}
+
+	var adaptiveLimit *adaptivePeerLimit
+	if SeedConfig.AdaptiveMaxPeers {
+		adaptiveLimit, err = newAdaptivePeerLimit(SeedConfig.MaxNumInboundPeers, SeedConfig.AdaptiveMaxPeersHeapLimit, filteredLogger.With("module", "adaptive"))
+		if err != nil {
+			return nil, err
+		}
+		peerFilters = append(peerFilters, adaptiveLimit.FilterPeer)
+	}
+	if portScan != nil {
+		peerFilters = append(peerFilters, portScan.FilterPeer)
+	}
+	if throttle != nil {
+		peerFilters = append(peerFilters, throttle.FilterPeer)
+	}
+	if audit != nil {
+		peerFilters = append(peerFilters, audit.FilterPeer)
+	}
+	if access != nil {
+		peerFilters = append(peerFilters, access.FilterPeer)
+	}
+	if pexBook.history != nil {
+		peerFilters = append(peerFilters, pexBook.history.FilterPeer)
+	}
+	swOpts = append(swOpts, p2p.SwitchPeerFilters(peerFilters...))
+
+	sw := p2p.NewSwitch(cfg, transport, swOpts...)
+	sw.SetLogger(filteredLogger.With("module", "switch"))
+	sw.SetNodeKey(nodeKey)
+	sw.SetAddrBook(pexBook)
+	if err := checkChannelPriorities(SeedConfig.ChannelPriorityMap, nodeInfo.Channels); err != nil {
+		return nil, err
+	}
+	prioritizedPexReactor, err := WithChannelPriorities(queuedPexReactor, SeedConfig.ChannelPriorityMap)
+	if err != nil {
+		return nil, err
+	}
+	sw.AddReactor("pex", prioritizedPexReactor)
+
+	if SeedConfig.MempoolChannelSupport {
+		prioritizedMempoolReactor, err := WithChannelPriorities(newMempoolReactor(), SeedConfig.ChannelPriorityMap)
+		if err != nil {
+			return nil, err
+		}
+		sw.AddReactor("mempool", prioritizedMempoolReactor)
+	}
+
+	if pexBook.metrics != nil {
+		sw.AddReactor("metrics", pexBook.metrics)
+		RegisterSwitchMetrics(metrics, sw, chainID)
+	}
+
+	if handshakes != nil {
+		sw.AddReactor("hooks", handshakes)
+	}
+
+	if telemetry.AccessLog {
+		if err := os.MkdirAll(filepath.Dir(SeedConfig.AccessLogFile), os.ModePerm); err != nil {
+			return nil, err
+		}
+		accessLog, err := newAccessLogReactor(SeedConfig.AccessLogFile, telemetry.DetailedAccessLog)
+		if err != nil {
+			return nil, err
+		}
+		sw.AddReactor("accesslog", accessLog)
+	}
+
+	// last
+	sw.SetNodeInfo(nodeInfo)
+
+	var adminServer *http.Server
+
+	start := func() error {
+		if pexBook.history != nil {
+			if err := pexBook.history.Open(); err != nil {
+				return fmt.Errorf("peer history: %w", err)
+			}
+		}
+		if err := transport.Listen(*addr); err != nil {
+			pexBook.history.Close()
+			return err
+		}
+		if err := sw.Start(); err != nil {
+			transport.Close()
+			pexBook.history.Close()
+			return err
+		}
+
+		if SeedConfig.AdminListenAddress != "" {
+			srv, err := StartAdminServer(SeedConfig.AdminListenAddress, pexBook, filteredLogger.With("module", "admin"))
+			if err != nil {
+				sw.Stop()
+				return fmt.Errorf("admin API: %w", err)
+			}
+			adminServer = srv
+			logger.Info("serving admin API", "addr", SeedConfig.AdminListenAddress)
+		}
+
+		if SeedConfig.UDPDiscovery {
+			if addrBookStrict {
+				logger.Info("udp discovery is disabled while the address book is strict")
+			} else {
+				discovery, err := newUDPDiscovery(SeedConfig.UDPDiscoveryAddress, SeedConfig.UDPDiscoveryPort, selfAddr, pexBook, filteredLogger.With("module", "discovery"))
+				if err != nil {
+					sw.Stop()
+					return err
+				}
+				go discovery.Run(sw.Quit())
+			}
+		}
+
+		if len(restartPeers) > 0 {
+			if err := sw.DialPeersAsync(restartPeers); err != nil {
+				logger.Error("failed to redial peers from before the restart", "err", err)
+			}
+		}
+
+		if adaptiveLimit != nil {
+			go adaptiveLimit.Run(sw, sw.Quit())
+		}
+		if latencyBudget != nil {
+			go latencyBudget.Run(sw.Quit())
+		}
+		if audit != nil {
+			go audit.Run(sw.Quit())
+		}
+		if crawl != nil {
+			// the crawler is what evicts, so only it has to warm up
+			pexBook.warmUp = startWarmUp(SeedConfig.WarmUpPeriod, filteredLogger.With("module", "book"))
+			go crawl.Run(sw.Quit())
+		}
+		if seedsURL != nil {
+			go seedsURL.Run(sw, sw.Quit())
+		}
+		if SeedConfig.AddrBookSaveInterval > 0 {
+			go pexBook.saveEvery(SeedConfig.AddrBookSaveInterval, sw.Quit())
+		}
+		if access != nil {
+			go access.Run(sw.Quit(), func() {
+				pexBook.pruneDenied()
+				for _, peer := range sw.Peers().List() {
+					if !access.Permits(peer.SocketAddr()) {
+						sw.StopPeerGracefully(peer)
+					}
+				}
+			})
+		}
+		return nil
+	}
+
+	stop := func() {
+		if adminServer != nil {
+			adminServer.Close()
+		}
+		if SeedConfig.ExportAddrBookOnShutdown {
+			if err := exportOnShutdown(SeedConfig.ExportOnShutdownFile, pexBook.Addresses(), SeedConfig.AddressShuffleOnExport); err != nil {
+				logger.Error("failed to export address book", "err", err)
+			}
+		}
+		if SeedConfig.GracefulRestartFile != "" {
+			state := captureRestartState(sw, pexBook, restartStats)
+			if err := WriteRestartState(SeedConfig.GracefulRestartFile, state); err != nil {
+				logger.Error("failed to write graceful restart file", "err", err)
+			}
+		}
+		if err := sw.Stop(); err != nil {
+			logger.Error("failed to stop switch", "err", err)
+		}
+		// the book stopped with the pex reactor, so this saves its final state
+		pexBook.Save()
+		transport.Close()
+		if err := pexBook.history.Close(); err != nil {
+			logger.Error("failed to close peer history", "err", err)
+		}
+	}
+
+	return &seedNode{chainID: chainID, selfAddr: selfAddr, sw: sw, book: pexBook, start: start, stop: stop}, nil
+}
@@ -0,0 +1,37 @@
+package seed
+
+import (
+	"testing"
+)
+
+// newTestConfig returns a config for a seed with metrics that listens on a
+// free loopback port and logs nothing
+func newTestConfig(t *testing.T) Config {
+	cfg := DefaultConfig(t.TempDir())
+	cfg.ChainID = "test"
+	cfg.ListenAddress = "tcp://" + freeAddress(t)
+	cfg.PrometheusListenAddr = freeAddress(t)
+	cfg.TelemetryLevel = TelemetryMinimal
+	cfg.LogLevel = "none"
+	return *cfg
+}
+
+func TestNewTwiceWithMetrics(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		s, err := New(newTestConfig(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		families, err := s.metrics.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for _, family := range families {
+			found = found || family.GetName() == "tinyseed_addrbook_size"
+		}
+		if !found {
+			t.Error("tinyseed_addrbook_size is not registered")
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package seed
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// checkSeedPublicKeys makes sure every entry of SeedPublicKeys, which maps
+// id@host:port to the node ID that seed must present, pins the ID the
+// address already names.  tendermint's transport refuses a seed presenting
+// any other ID than the one we dial, so the address is what enforces the
+// pin and a mismatch could never connect.
+func checkSeedPublicKeys(pins map[string]string) error {
+	for seed, id := range pins {
+		addr, err := p2p.NewNetAddressString(seed)
+		if err != nil {
+			return fmt.Errorf("seed_public_keys: %w", err)
+		}
+		if bz, err := hex.DecodeString(id); err != nil || len(bz) != p2p.IDByteLength {
+			return fmt.Errorf("seed_public_keys: %q is not a valid node ID for %s", id, seed)
+		}
+		if addr.ID != p2p.ID(id) {
+			return fmt.Errorf("seed_public_keys: %s is pinned to node ID %s but its address names %s", addr.DialString(), id, addr.ID)
+		}
+	}
+	return nil
+}
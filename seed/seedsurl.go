@@ -0,0 +1,175 @@
+package seed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	tmstrings "github.com/tendermint/tendermint/libs/strings"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// seedsURLTimeout bounds a fetch of SeedsURL
+const seedsURLTimeout = 30 * time.Second
+
+// maxSeedsURLBody is the largest seed list we read
+const maxSeedsURLBody = 4 << 20
+
+// chainRegistryChain is the part of a cosmos/chain-registry chain.json
+// holding the seeds
+type chainRegistryChain struct {
+	Peers struct {
+		Seeds []struct {
+			ID      string `json:"id"`
+			Address string `json:"address"`
+		} `json:"seeds"`
+	} `json:"peers"`
+}
+
+// ParseSeeds reads id@host:port addresses from a chain-registry chain.json
+// or from plain text listing them separated by commas or newlines
+func ParseSeeds(body []byte) ([]string, error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		var chain chainRegistryChain
+		if err := json.Unmarshal(trimmed, &chain); err != nil {
+			return nil, err
+		}
+		seeds := make([]string, 0, len(chain.Peers.Seeds))
+		for _, seed := range chain.Peers.Seeds {
+			seeds = append(seeds, seed.ID+"@"+seed.Address)
+		}
+		return seeds, nil
+	}
+
+	var seeds []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seeds = append(seeds, tmstrings.SplitAndTrim(line, ",", " ")...)
+	}
+	return seeds, scanner.Err()
+}
+
+// FetchSeeds downloads and parses the seed list at url
+func FetchSeeds(url string) ([]string, error) {
+	client := &http.Client{Timeout: seedsURLTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSeedsURLBody))
+	if err != nil {
+		return nil, err
+	}
+	return ParseSeeds(body)
+}
+
+// seedsRefresher keeps the seeds listed at a URL in the book and dials the
+// ones it has not seen before.  The PEX reactor only dials the static seeds
+// it was built with, once at startup.
+type seedsRefresher struct {
+	url      string
+	interval time.Duration
+	book     *seedBook
+	logger   log.Logger
+
+	// known are the static seeds and those already fetched
+	known map[string]struct{}
+}
+
+// newSeedsRefresher fetches seedsURL every interval, or only at startup
+// when interval is 0.  static are the seeds from the config.
+func newSeedsRefresher(seedsURL string, interval time.Duration, static []string, book *seedBook, logger log.Logger) (*seedsRefresher, error) {
+	u, err := url.Parse(seedsURL)
+	if err != nil {
+		return nil, fmt.Errorf("seeds_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("seeds_url: %q is not an http or https URL", seedsURL)
+	}
+	if interval < 0 {
+		return nil, fmt.Errorf("seeds_url_refresh_interval must not be negative, got %v", interval)
+	}
+
+	known := make(map[string]struct{}, len(static))
+	for _, seed := range static {
+		known[seed] = struct{}{}
+	}
+	return &seedsRefresher{
+		url:      seedsURL,
+		interval: interval,
+		book:     book,
+		logger:   logger,
+		known:    known,
+	}, nil
+}
+
+// Run fetches the seeds right away and then every interval until quit is
+// closed
+func (r *seedsRefresher) Run(sw *p2p.Switch, quit <-chan struct{}) {
+	r.refresh(sw)
+	if r.interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.refresh(sw)
+		case <-quit:
+			return
+		}
+	}
+}
+
+// refresh fetches the seed list once.  A failed fetch keeps the seeds we
+// already have.
+func (r *seedsRefresher) refresh(sw *p2p.Switch) {
+	seeds, err := FetchSeeds(r.url)
+	if err != nil {
+		r.logger.Error("failed to fetch seeds", "url", r.url, "err", err)
+		return
+	}
+
+	var added []string
+	for _, seed := range seeds {
+		if _, ok := r.known[seed]; ok {
+			continue
+		}
+		addr, err := p2p.NewNetAddressString(seed)
+		if err != nil {
+			r.logger.Error("ignoring fetched seed", "seed", seed, "err", err)
+			continue
+		}
+		r.known[seed] = struct{}{}
+		r.book.addSeedID(addr.ID)
+		added = append(added, seed)
+	}
+	r.logger.Info("fetched seeds", "url", r.url, "listed", len(seeds), "new", len(added))
+	if len(added) == 0 {
+		return
+	}
+
+	// adds them to the book as well, for the crawl to come back to
+	if err := sw.DialPeersAsync(added); err != nil {
+		r.logger.Error("failed to dial fetched seeds", "err", err)
+	}
+}
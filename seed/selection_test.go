@@ -0,0 +1,90 @@
+package seed
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// selectionRate is the PEX request rate BenchmarkGetSelection sustains
+const selectionRate = 2000
+
+func TestSelectionCacheSurvivesFewChanges(t *testing.T) {
+	b := newTestSeedBook(t, 1, false)
+	b.selectionTTL = time.Hour
+	b.selectionMaxChanges = 3
+	addrs := testAddrs(100)
+	for _, addr := range addrs[1:50] {
+		if err := b.addAddress(addr, addrs[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := b.GetSelectionWithBias(30)
+	b.addAddress(addrs[50], addrs[0]) //nolint:errcheck
+	b.addAddress(addrs[51], addrs[0]) //nolint:errcheck
+	if second := b.GetSelectionWithBias(30); &second[0] != &first[0] {
+		t.Fatal("the selection was recomputed before selectionMaxChanges changes")
+	}
+	b.addAddress(addrs[52], addrs[0]) //nolint:errcheck
+	if third := b.GetSelectionWithBias(30); &third[0] == &first[0] {
+		t.Fatal("the selection was served again after selectionMaxChanges changes")
+	}
+}
+
+// BenchmarkGetSelection answers PEX requests at selectionRate per second
+// while addresses keep arriving, reporting how long each request took
+func BenchmarkGetSelection(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"uncached", 0},
+		{"cached", 30 * time.Second},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			book := newTestSeedBook(b, 1, false)
+			book.selectionTTL = bc.ttl
+			book.selectionMaxChanges = DefaultConfig("").PeerListCacheMaxChanges
+			addrs := testAddrs(5000)
+			src := addrs[0]
+			for _, addr := range addrs[1:2000] {
+				book.addAddress(addr, src) //nolint:errcheck
+			}
+
+			// a tenth as many addresses arrive as requests
+			quit := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ticker := time.NewTicker(10 * time.Second / selectionRate)
+				defer ticker.Stop()
+				for i := 2000; ; i++ {
+					select {
+					case <-ticker.C:
+						book.addAddress(addrs[i%len(addrs)], src) //nolint:errcheck
+					case <-quit:
+						return
+					}
+				}
+			}()
+
+			ticker := time.NewTicker(time.Second / selectionRate)
+			defer ticker.Stop()
+			var busy time.Duration
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				<-ticker.C
+				start := time.Now()
+				book.GetSelectionWithBias(30)
+				busy += time.Since(start)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(busy.Nanoseconds())/float64(b.N), "latency-ns/req")
+
+			close(quit)
+			wg.Wait()
+		})
+	}
+}
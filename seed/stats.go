@@ -0,0 +1,58 @@
+package seed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// statsTimeout bounds the request to a running seed's peers API
+const statsTimeout = 10 * time.Second
+
+// ReadPeerHistory returns the records in the peer history database at
+// path.  While a running seed holds the database they are fetched from GET
+// /history on that seed's peers API at api instead.
+func ReadPeerHistory(path, api, chainID string) ([]PeerHistory, error) {
+	if path == "" {
+		return nil, errors.New("peer_history_file is not set")
+	}
+	records, err := LoadPeerHistory(path)
+	if errors.Is(err, bolt.ErrTimeout) {
+		if api == "" {
+			return nil, fmt.Errorf("%s is in use by a running seed; set peers_listen_addr or --api to query it instead", path)
+		}
+		return fetchPeerHistory(api, chainID)
+	}
+	return records, err
+}
+
+// fetchPeerHistory asks the peers API at addr for chainID's history
+func fetchPeerHistory(addr, chainID string) ([]PeerHistory, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	client := &http.Client{Timeout: statsTimeout}
+	resp, err := client.Get(strings.TrimRight(addr, "/") + "/history?chain_id=" + url.QueryEscape(chainID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peers API returned %s", resp.Status)
+	}
+
+	var chains []ChainHistory
+	if err := json.NewDecoder(resp.Body).Decode(&chains); err != nil {
+		return nil, err
+	}
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("peers API has no history for %s", chainID)
+	}
+	return chains[0].Peers, nil
+}
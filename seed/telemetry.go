@@ -0,0 +1,421 @@
+package seed
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// Telemetry levels accepted by Config.TelemetryLevel.  TelemetryFull adds
+// the detailed access log to TelemetryMinimal; there is no OTEL tracing yet,
+// so no level exports traces.
+const (
+	TelemetryNone    = "none"
+	TelemetryMinimal = "minimal"
+	TelemetryFull    = "full"
+)
+
+// metricsNamespace prefixes every metric exported by the seed
+const metricsNamespace = "tinyseed"
+
+// telemetry is the effective telemetry setup once TelemetryLevel and the
+// individual overrides have been applied
+type telemetry struct {
+	Prometheus        bool
+	AccessLog         bool
+	DetailedAccessLog bool
+}
+
+// resolveTelemetry applies TelemetryLevel and then any individual overrides
+func resolveTelemetry(SeedConfig Config) (telemetry, error) {
+	var t telemetry
+	switch SeedConfig.TelemetryLevel {
+	case "", TelemetryNone:
+	case TelemetryMinimal:
+		t.Prometheus = true
+		t.AccessLog = true
+	case TelemetryFull:
+		t.Prometheus = true
+		t.AccessLog = true
+		t.DetailedAccessLog = true
+	default:
+		return t, fmt.Errorf("unknown telemetry level %q", SeedConfig.TelemetryLevel)
+	}
+
+	if SeedConfig.Prometheus != nil {
+		t.Prometheus = *SeedConfig.Prometheus
+	}
+	if SeedConfig.AccessLog != nil {
+		t.AccessLog = *SeedConfig.AccessLog
+	}
+	if SeedConfig.DetailedAccessLog != nil {
+		t.DetailedAccessLog = *SeedConfig.DetailedAccessLog
+	}
+	// detailed entries are written to the same access log
+	t.DetailedAccessLog = t.DetailedAccessLog && t.AccessLog
+
+	return t, nil
+}
+
+// metricsRegistry holds the metrics of one Seed, so that several seeds can
+// live in one process.  Every chain registers its own collectors, labelled
+// with its chain_id, except tendermint's p2p metrics, which the chains
+// share.
+type metricsRegistry struct {
+	*prometheus.Registry
+
+	p2p *p2p.Metrics
+}
+
+// newMetricsRegistry returns a registry holding the Go runtime and process
+// metrics and tendermint's p2p metrics.  tendermint only registers the
+// latter on the default registry, so they are built here the same way.
+func newMetricsRegistry() *metricsRegistry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	p2pOpts := func(name, help string) prometheus.Opts {
+		return prometheus.Opts{Namespace: metricsNamespace, Subsystem: p2p.MetricsSubsystem, Name: name, Help: help}
+	}
+	gauge := func(opts prometheus.Opts, labels ...string) *kitprometheus.Gauge {
+		vec := prometheus.NewGaugeVec(prometheus.GaugeOpts(opts), append([]string{"chain_id"}, labels...))
+		reg.MustRegister(vec)
+		return kitprometheus.NewGauge(vec)
+	}
+	counter := func(opts prometheus.Opts, labels ...string) *kitprometheus.Counter {
+		vec := prometheus.NewCounterVec(prometheus.CounterOpts(opts), append([]string{"chain_id"}, labels...))
+		reg.MustRegister(vec)
+		return kitprometheus.NewCounter(vec)
+	}
+
+	return &metricsRegistry{
+		Registry: reg,
+		p2p: &p2p.Metrics{
+			Peers:                 gauge(p2pOpts("peers", "Number of peers.")),
+			PeerReceiveBytesTotal: counter(p2pOpts("peer_receive_bytes_total", "Number of bytes received from a given peer."), "peer_id", "chID"),
+			PeerSendBytesTotal:    counter(p2pOpts("peer_send_bytes_total", "Number of bytes sent to a given peer."), "peer_id", "chID"),
+			PeerPendingSendBytes:  gauge(p2pOpts("peer_pending_send_bytes", "Number of pending bytes to be sent to a given peer."), "peer_id"),
+			NumTxs:                gauge(p2pOpts("num_txs", "Number of transactions submitted by each peer."), "peer_id"),
+		},
+	}
+}
+
+// chainP2PMetrics returns tendermint's p2p metrics labelled with chainID
+func (r *metricsRegistry) chainP2PMetrics(chainID string) *p2p.Metrics {
+	return &p2p.Metrics{
+		Peers:                 r.p2p.Peers.With("chain_id", chainID),
+		PeerReceiveBytesTotal: r.p2p.PeerReceiveBytesTotal.With("chain_id", chainID),
+		PeerSendBytesTotal:    r.p2p.PeerSendBytesTotal.With("chain_id", chainID),
+		PeerPendingSendBytes:  r.p2p.PeerPendingSendBytes.With("chain_id", chainID),
+		NumTxs:                r.p2p.NumTxs.With("chain_id", chainID),
+	}
+}
+
+// RegisterAddrBookMetrics exports the size of book to reg
+func RegisterAddrBookMetrics(reg prometheus.Registerer, book pex.AddrBook, chainID string) {
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "addrbook",
+		Name:        "size",
+		Help:        "Number of addresses in the address book.",
+		ConstLabels: prometheus.Labels{"chain_id": chainID},
+	}, func() float64 {
+		return float64(book.Size())
+	}))
+}
+
+// RegisterSwitchMetrics exports the inbound and outbound peer counts of sw
+// and the seed's uptime to reg
+func RegisterSwitchMetrics(reg prometheus.Registerer, sw *p2p.Switch, chainID string) {
+	labels := prometheus.Labels{"chain_id": chainID}
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "peers",
+		Name:        "inbound",
+		Help:        "Number of connected inbound peers.",
+		ConstLabels: labels,
+	}, func() float64 {
+		_, inbound, _ := sw.NumPeers()
+		return float64(inbound)
+	}))
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   metricsNamespace,
+		Subsystem:   "peers",
+		Name:        "outbound",
+		Help:        "Number of connected outbound peers.",
+		ConstLabels: labels,
+	}, func() float64 {
+		outbound, _, _ := sw.NumPeers()
+		return float64(outbound)
+	}))
+
+	started := time.Now()
+	reg.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   metricsNamespace,
+		Name:        "uptime_seconds_total",
+		Help:        "Seconds since the seed started.",
+		ConstLabels: labels,
+	}, func() float64 {
+		return time.Since(started).Seconds()
+	}))
+}
+
+// seedMetrics is a channel-less reactor counting successful dials.  The
+// book reports failed dials and served PEX requests to it, and the crawler
+// its failed probes.  A nil
+// *seedMetrics counts nothing.
+type seedMetrics struct {
+	p2p.BaseReactor
+
+	dialAttempts prometheus.Counter
+	dialFailures prometheus.Counter
+	pexServed    prometheus.Counter
+
+	// crawlFailures counts the crawler's failed probes apart from dials
+	crawlFailures prometheus.Counter
+
+	// addrsServed counts the addresses handed out by location when a GeoIP
+	// database is configured
+	addrsServed *prometheus.CounterVec
+}
+
+func newSeedMetrics(chainID string) *seedMetrics {
+	labels := prometheus.Labels{"chain_id": chainID}
+	m := &seedMetrics{
+		dialAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "dial",
+			Name:        "attempts_total",
+			Help:        "Number of outbound dials, successful or not.",
+			ConstLabels: labels,
+		}),
+		dialFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "dial",
+			Name:        "failures_total",
+			Help:        "Number of outbound dials that failed.",
+			ConstLabels: labels,
+		}),
+		crawlFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "crawl",
+			Name:        "failures_total",
+			Help:        "Number of crawler probes that failed.",
+			ConstLabels: labels,
+		}),
+		pexServed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "pex",
+			Name:        "requests_served_total",
+			Help:        "Number of PEX requests answered with a selection of addresses.",
+			ConstLabels: labels,
+		}),
+		addrsServed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "pex",
+			Name:        "addresses_served_total",
+			Help:        "Number of addresses handed out in PEX responses, by country and continent. Only counted with a GeoIP database.",
+			ConstLabels: labels,
+		}, []string{"country", "continent"}),
+	}
+	m.BaseReactor = *p2p.NewBaseReactor("Metrics", m)
+	return m
+}
+
+// RegisterMetrics exports tinyseed_dial_attempts_total,
+// tinyseed_dial_failures_total, tinyseed_crawl_failures_total,
+// tinyseed_pex_requests_served_total and tinyseed_pex_addresses_served_total
+// to reg
+func (m *seedMetrics) RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(m.dialAttempts, m.dialFailures, m.crawlFailures, m.pexServed, m.addrsServed)
+}
+
+// AddPeer implements p2p.Reactor
+func (m *seedMetrics) AddPeer(peer p2p.Peer) {
+	if peer.IsOutbound() {
+		m.dialAttempts.Inc()
+	}
+}
+
+// dialFailed counts a failed dial
+func (m *seedMetrics) dialFailed() {
+	if m == nil {
+		return
+	}
+	m.dialAttempts.Inc()
+	m.dialFailures.Inc()
+}
+
+// crawlFailed counts a failed crawler probe
+func (m *seedMetrics) crawlFailed() {
+	if m == nil {
+		return
+	}
+	m.crawlFailures.Inc()
+}
+
+// served counts an answered PEX request
+func (m *seedMetrics) served() {
+	if m == nil {
+		return
+	}
+	m.pexServed.Inc()
+}
+
+// servedAddrs counts the addresses of a PEX response by location
+func (m *seedMetrics) servedAddrs(locs []GeoLocation) {
+	if m == nil {
+		return
+	}
+	for _, loc := range locs {
+		m.addrsServed.WithLabelValues(geoLabel(loc.Country), geoLabel(loc.Continent)).Inc()
+	}
+}
+
+// geoLabel is the label value for a country or continent code
+func geoLabel(code string) string {
+	if code == "" {
+		return "unknown"
+	}
+	return code
+}
+
+// geoCollector exports the size of the address book by location
+type geoCollector struct {
+	book *seedBook
+	desc *prometheus.Desc
+}
+
+// RegisterGeoMetrics exports tinyseed_addrbook_addresses by country and
+// continent to reg
+func RegisterGeoMetrics(reg prometheus.Registerer, book *seedBook, chainID string) {
+	reg.MustRegister(&geoCollector{
+		book: book,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "addrbook", "addresses"),
+			"Number of addresses in the address book, by country and continent.",
+			[]string{"country", "continent"},
+			prometheus.Labels{"chain_id": chainID},
+		),
+	})
+}
+
+// Describe implements prometheus.Collector
+func (c *geoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *geoCollector) Collect(ch chan<- prometheus.Metric) {
+	counts := make(map[[2]string]int)
+	for _, ka := range c.book.candidates() {
+		counts[[2]string{geoLabel(ka.Country), geoLabel(ka.Continent)}]++
+	}
+	for labels, n := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), labels[0], labels[1])
+	}
+}
+
+// serveHTTP listens on addr and serves handler in the background
+func serveHTTP(addr string, handler http.Handler) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Addr: addr, Handler: handler}
+	go srv.Serve(ln)
+	return srv, nil
+}
+
+// StartMetricsServer serves the metrics gathered by gatherer on addr
+func StartMetricsServer(addr string, gatherer prometheus.Gatherer) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	return serveHTTP(addr, mux)
+}
+
+// accessLogReactor is a channel-less reactor that records every peer the
+// switch accepts or dials, and when it goes away
+type accessLogReactor struct {
+	p2p.BaseReactor
+
+	detailed bool
+
+	mtx       sync.Mutex
+	out       *os.File
+	connected map[p2p.ID]time.Time
+}
+
+// newAccessLogReactor appends access log entries to the file at path
+func newAccessLogReactor(path string, detailed bool) (*accessLogReactor, error) {
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &accessLogReactor{
+		detailed:  detailed,
+		out:       out,
+		connected: make(map[p2p.ID]time.Time),
+	}
+	r.BaseReactor = *p2p.NewBaseReactor("AccessLog", r)
+	return r, nil
+}
+
+// OnStop implements service.Service
+func (r *accessLogReactor) OnStop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.out.Close()
+}
+
+// AddPeer implements p2p.Reactor
+func (r *accessLogReactor) AddPeer(peer p2p.Peer) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.connected[peer.ID()] = time.Now()
+
+	line := fmt.Sprintf("%s connect %s %s %s",
+		time.Now().UTC().Format(time.RFC3339), direction(peer), peer.ID(), peer.RemoteAddr())
+	if r.detailed {
+		if info, ok := peer.NodeInfo().(p2p.DefaultNodeInfo); ok {
+			line += fmt.Sprintf(" network=%s version=%s moniker=%q channels=%X",
+				info.Network, info.Version, info.Moniker, info.Channels)
+		}
+	}
+	fmt.Fprintln(r.out, line)
+}
+
+// RemovePeer implements p2p.Reactor
+func (r *accessLogReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	line := fmt.Sprintf("%s disconnect %s %s %s",
+		time.Now().UTC().Format(time.RFC3339), direction(peer), peer.ID(), peer.RemoteAddr())
+	if connected, ok := r.connected[peer.ID()]; ok {
+		delete(r.connected, peer.ID())
+		if r.detailed {
+			line += fmt.Sprintf(" duration=%s reason=%q", time.Since(connected), fmt.Sprint(reason))
+		}
+	}
+	fmt.Fprintln(r.out, line)
+}
+
+// direction describes who initiated the connection to peer
+func direction(peer p2p.Peer) string {
+	if peer.IsOutbound() {
+		return "outbound"
+	}
+	return "inbound"
+}
@@ -0,0 +1,140 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// throttleWindow is how far back inbound connections are counted per IP
+const throttleWindow = time.Minute
+
+// tendermintHandshakeTimeout is the fixed handshake timeout of the
+// tendermint transport, which a throttle can only shorten
+const tendermintHandshakeTimeout = 3 * time.Second
+
+// inboundThrottle refuses inbound connections from IPs that connect too
+// often and caps how many handshakes are in flight.  A handshake is in
+// flight from when every connection filter has accepted it until the peer
+// filter sees it, or until the handshake timeout, at which point the
+// connection is closed.
+type inboundThrottle struct {
+	listenPort    uint16
+	perIPLimit    int
+	maxHandshakes int
+	timeout       time.Duration
+
+	mtx      sync.Mutex
+	connects *ipWindow
+	inFlight map[string]*time.Timer
+}
+
+// newInboundThrottle watches inbound connections to listenPort.  A zero
+// limit disables that check and a zero timeout keeps tendermint's.
+func newInboundThrottle(listenPort uint16, perIPLimit, maxHandshakes int, timeout time.Duration) (*inboundThrottle, error) {
+	if perIPLimit < 0 || maxHandshakes < 0 || timeout < 0 {
+		return nil, errors.New("inbound_conns_per_ip_per_minute, max_concurrent_handshakes and inbound_handshake_timeout must not be negative")
+	}
+	if timeout > tendermintHandshakeTimeout {
+		return nil, fmt.Errorf("inbound_handshake_timeout cannot exceed tendermint's own handshake timeout of %v, got %v", tendermintHandshakeTimeout, timeout)
+	}
+	if timeout == 0 {
+		timeout = tendermintHandshakeTimeout
+	}
+	return &inboundThrottle{
+		listenPort:    listenPort,
+		perIPLimit:    perIPLimit,
+		maxHandshakes: maxHandshakes,
+		timeout:       timeout,
+		connects:      newIPWindow(throttleWindow),
+		inFlight:      make(map[string]*time.Timer),
+	}, nil
+}
+
+// FilterConn is a p2p.ConnFilterFunc refusing inbound connections over the
+// limits.  Dialed connections are told apart by their local port.  It only
+// checks for a free handshake slot; StartHandshake takes it once every other
+// filter has accepted the connection.
+func (t *inboundThrottle) FilterConn(_ p2p.ConnSet, c net.Conn, _ []net.IP) error {
+	ip, ok := t.inbound(c)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.perIPLimit > 0 {
+		if connects := t.connects.Count(ip, now); connects >= t.perIPLimit {
+			return fmt.Errorf("%s opened %d connections within %v", ip, connects, throttleWindow)
+		}
+		t.connects.Add(ip, now)
+	}
+	return t.checkSlot()
+}
+
+// StartHandshake is a p2p.ConnFilterFunc putting the handshake of c in
+// flight.  It must run in a sequentialConnFilter after every filter that
+// may reject c, so that slots are only held by real handshakes.
+func (t *inboundThrottle) StartHandshake(_ p2p.ConnSet, c net.Conn, _ []net.IP) error {
+	if _, ok := t.inbound(c); !ok {
+		return nil
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	// another connection may have taken the last slot while c was queued
+	if err := t.checkSlot(); err != nil {
+		return err
+	}
+	key := c.RemoteAddr().String()
+	t.inFlight[key] = time.AfterFunc(t.timeout, func() {
+		t.mtx.Lock()
+		delete(t.inFlight, key)
+		t.mtx.Unlock()
+		c.Close()
+	})
+	return nil
+}
+
+// inbound returns the remote IP of c if it was accepted on listenPort
+func (t *inboundThrottle) inbound(c net.Conn) (string, bool) {
+	local, ok := c.LocalAddr().(*net.TCPAddr)
+	if !ok || local.Port != int(t.listenPort) {
+		return "", false
+	}
+	remote, ok := c.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return "", false
+	}
+	return remote.IP.String(), true
+}
+
+// checkSlot refuses a handshake when max_concurrent_handshakes are already
+// in flight.  t.mtx must be held.
+func (t *inboundThrottle) checkSlot() error {
+	if t.maxHandshakes > 0 && len(t.inFlight) >= t.maxHandshakes {
+		return fmt.Errorf("%d handshakes already in flight", len(t.inFlight))
+	}
+	return nil
+}
+
+// FilterPeer is a p2p.PeerFilterFunc ending the handshake of peer.  It
+// never rejects.
+func (t *inboundThrottle) FilterPeer(_ p2p.IPeerSet, peer p2p.Peer) error {
+	key := peer.SocketAddr().DialString()
+
+	t.mtx.Lock()
+	if timer, ok := t.inFlight[key]; ok {
+		timer.Stop()
+		delete(t.inFlight, key)
+	}
+	t.mtx.Unlock()
+	return nil
+}
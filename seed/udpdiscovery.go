@@ -0,0 +1,113 @@
+package seed
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+const (
+	// udpAnnounceInterval is how often we announce ourselves
+	udpAnnounceInterval = 30 * time.Second
+
+	// udpMaxPayload comfortably fits id@host:port
+	udpMaxPayload = 512
+)
+
+// udpDiscovery announces selfAddr as id@host:port datagrams on the local
+// network and adds the addresses other seeds announce to the book
+type udpDiscovery struct {
+	selfAddr *p2p.NetAddress
+	book     pex.AddrBook
+	logger   log.Logger
+
+	conn  *net.UDPConn
+	group *net.UDPAddr
+}
+
+// newUDPDiscovery binds to port, joining the group if address is multicast
+// and broadcasting to it otherwise
+func newUDPDiscovery(address string, port int, selfAddr *p2p.NetAddress, book pex.AddrBook, logger log.Logger) (*udpDiscovery, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid udp_discovery_address %q", address)
+	}
+	group := &net.UDPAddr{IP: ip, Port: port}
+
+	var conn *net.UDPConn
+	var err error
+	if ip.IsMulticast() {
+		conn, err = net.ListenMulticastUDP("udp4", nil, group)
+	} else {
+		conn, err = net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &udpDiscovery{
+		selfAddr: selfAddr,
+		book:     book,
+		logger:   logger,
+		conn:     conn,
+		group:    group,
+	}, nil
+}
+
+// Run announces and listens until quit is closed
+func (d *udpDiscovery) Run(quit <-chan struct{}) {
+	go d.receive()
+	defer d.conn.Close()
+
+	ticker := time.NewTicker(udpAnnounceInterval)
+	defer ticker.Stop()
+
+	d.announce()
+	for {
+		select {
+		case <-ticker.C:
+			d.announce()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// announce sends our address to the group
+func (d *udpDiscovery) announce() {
+	if _, err := d.conn.WriteToUDP([]byte(d.selfAddr.String()), d.group); err != nil {
+		d.logger.Debug("failed to announce", "group", d.group, "err", err)
+	}
+}
+
+// receive adds announced addresses to the book until the socket is closed
+func (d *udpDiscovery) receive() {
+	buf := make([]byte, udpMaxPayload)
+	for {
+		n, from, err := d.conn.ReadFromUDP(buf)
+		if err != nil {
+			// closed by Run
+			return
+		}
+
+		addr, err := p2p.NewNetAddressString(strings.TrimSpace(string(buf[:n])))
+		if err != nil {
+			d.logger.Debug("ignoring malformed announcement", "from", from, "err", err)
+			continue
+		}
+		if addr.ID == d.selfAddr.ID {
+			continue
+		}
+
+		if err := d.book.AddAddress(addr, addr); err != nil {
+			d.logger.Debug("failed to add discovered address", "addr", addr, "err", err)
+			continue
+		}
+		d.logger.Debug("discovered peer", "addr", addr, "from", from)
+	}
+}
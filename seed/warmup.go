@@ -0,0 +1,33 @@
+package seed
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// warmUp tracks the period after startup during which the address book is
+// allowed to grow freely
+type warmUp struct {
+	until time.Time
+}
+
+// startWarmUp begins a warm-up period of the given length and logs when it
+// starts and ends.  A zero period disables warm-up.
+func startWarmUp(period time.Duration, logger log.Logger) *warmUp {
+	w := &warmUp{until: time.Now().Add(period)}
+	if period <= 0 {
+		return w
+	}
+
+	logger.Info("warm-up started, address book eviction disabled", "period", period)
+	time.AfterFunc(period, func() {
+		logger.Info("warm-up finished, address book eviction enabled")
+	})
+	return w
+}
+
+// Active reports whether the warm-up period is still running
+func (w *warmUp) Active() bool {
+	return time.Now().Before(w.until)
+}
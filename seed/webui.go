@@ -0,0 +1,172 @@
+package seed
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// webUISampleInterval is how often the web UI records each address book's
+// size, and webUIHistoryLength how many samples it keeps: six hours' worth
+const (
+	webUISampleInterval = time.Minute
+	webUIHistoryLength  = 360
+)
+
+//go:embed webui
+var webUIFiles embed.FS
+
+// ChainStatus is a chain as served by GET /api/status
+type ChainStatus struct {
+	ChainID     string          `json:"chain_id"`
+	NodeID      p2p.ID          `json:"node_id"`
+	Address     string          `json:"address"`
+	Running     bool            `json:"running"`
+	BookSize    int             `json:"book_size"`
+	LastPEX     time.Time       `json:"last_pex"`
+	PEXEvents   uint64          `json:"pex_events"`
+	Peers       []ConnectedPeer `json:"peers"`
+	BookHistory []BookSample    `json:"book_history"`
+}
+
+// ConnectedPeer is a peer the switch is connected to
+type ConnectedPeer struct {
+	ID        p2p.ID        `json:"id"`
+	Address   string        `json:"address"`
+	Outbound  bool          `json:"outbound"`
+	Moniker   string        `json:"moniker"`
+	Connected time.Duration `json:"connected_ns"`
+}
+
+// BookSample is the size of an address book and the PEX events since the
+// previous sample
+type BookSample struct {
+	Time      time.Time `json:"time"`
+	Size      int       `json:"size"`
+	PEXEvents uint64    `json:"pex_events"`
+}
+
+// bookHistory samples a chain's address book for the web UI
+type bookHistory struct {
+	mtx     sync.Mutex
+	samples []BookSample
+	events  uint64
+}
+
+// run samples book every webUISampleInterval until quit is closed
+func (h *bookHistory) run(book *seedBook, quit <-chan struct{}) {
+	h.sample(book)
+
+	ticker := time.NewTicker(webUISampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.sample(book)
+		case <-quit:
+			return
+		}
+	}
+}
+
+func (h *bookHistory) sample(book *seedBook) {
+	events := book.PEXEvents()
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.samples = append(h.samples, BookSample{Time: time.Now(), Size: book.Size(), PEXEvents: events - h.events})
+	h.events = events
+	if len(h.samples) > webUIHistoryLength {
+		h.samples = h.samples[len(h.samples)-webUIHistoryLength:]
+	}
+}
+
+func (h *bookHistory) get() []BookSample {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return append([]BookSample(nil), h.samples...)
+}
+
+// chainStatus reports on node for the web UI
+func chainStatus(node *seedNode, history *bookHistory) ChainStatus {
+	status := ChainStatus{
+		ChainID:     node.chainID,
+		NodeID:      node.selfAddr.ID,
+		Address:     node.selfAddr.String(),
+		Running:     node.sw.IsRunning(),
+		BookSize:    node.book.Size(),
+		LastPEX:     node.book.LastPEXActivity(),
+		PEXEvents:   node.book.PEXEvents(),
+		Peers:       []ConnectedPeer{},
+		BookHistory: history.get(),
+	}
+	for _, peer := range node.sw.Peers().List() {
+		connected := ConnectedPeer{
+			ID:        peer.ID(),
+			Address:   peer.SocketAddr().String(),
+			Outbound:  peer.IsOutbound(),
+			Connected: peer.Status().Duration,
+		}
+		if info, ok := peer.NodeInfo().(p2p.DefaultNodeInfo); ok {
+			connected.Moniker = info.Moniker
+		}
+		status.Peers = append(status.Peers, connected)
+	}
+	sort.Slice(status.Peers, func(i, j int) bool {
+		return status.Peers[i].Connected > status.Peers[j].Connected
+	})
+	return status
+}
+
+// StartWebUIServer serves a status page for nodes on addr, at / with the
+// data it shows at GET /api/status.  It samples each address book until
+// the node's switch stops.
+func StartWebUIServer(addr string, nodes []*seedNode) (*http.Server, error) {
+	histories := make([]*bookHistory, len(nodes))
+	for i := range nodes {
+		histories[i] = &bookHistory{}
+	}
+
+	static, err := fs.Sub(webUIFiles, "webui")
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		chains := make([]ChainStatus, 0, len(nodes))
+		for i, node := range nodes {
+			chains = append(chains, chainStatus(node, histories[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Version string        `json:"version"`
+			Chains  []ChainStatus `json:"chains"`
+		}{Version, chains})
+	})
+
+	srv, err := serveHTTP(addr, mux)
+	if err != nil {
+		return nil, err
+	}
+	for i, node := range nodes {
+		go histories[i].run(node.book, node.sw.Quit())
+	}
+	return srv, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/notional-labs/tinyseed/seed"
+	"github.com/spf13/cobra"
+)
+
+// newStatsCmd implements `tinyseed stats`, summarising the peer history.
+// The database is read directly when the seed is stopped, and through GET
+// /history on peers_listen_addr while a running seed holds it.
+func newStatsCmd(opts *cliOptions) *cobra.Command {
+	var file, api string
+	var asJSON bool
+	var top int
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarise the peer history recorded in peer_history_file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := opts.loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("file") {
+				file = cfg.PeerHistoryFile
+			}
+			if !cmd.Flags().Changed("api") {
+				api = cfg.PeersListenAddress
+			}
+
+			records, err := seed.ReadPeerHistory(file, api, cfg.ChainID)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(records)
+			}
+			printPeerStats(cmd.OutOrStdout(), records, top)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&file, "file", "", "peer history database to read (defaults to peer_history_file)")
+	flags.StringVar(&api, "api", "", "peers API of the running seed, used when the database is locked (defaults to peers_listen_addr)")
+	flags.BoolVar(&asJSON, "json", false, "print every record as JSON instead of a summary")
+	flags.IntVar(&top, "top", 10, "versions and monikers to list")
+	return cmd
+}
+
+// printPeerStats summarises records: how many nodes were seen, how many
+// came and went, and which versions they run
+func printPeerStats(w io.Writer, records []seed.PeerHistory, top int) {
+	now := time.Now()
+	windows := []struct {
+		name string
+		d    time.Duration
+	}{{"24h", 24 * time.Hour}, {"7d", 7 * 24 * time.Hour}, {"30d", 30 * 24 * time.Hour}}
+
+	versions := make(map[string]int)
+	monikers := make(map[string]int)
+	var connected, reachable int
+	for _, record := range records {
+		if record.Connections > 0 {
+			connected++
+			versions[record.Version]++
+			monikers[record.Moniker]++
+		}
+		if !record.LastSuccess.IsZero() {
+			reachable++
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "nodes recorded\t%d\n", len(records))
+	fmt.Fprintf(tw, "ever dialed successfully\t%d\n", reachable)
+	fmt.Fprintf(tw, "ever completed a handshake\t%d\n", connected)
+	for _, window := range windows {
+		var seen, first, gone int
+		for _, record := range records {
+			if now.Sub(record.LastSeen) <= window.d {
+				seen++
+			} else if now.Sub(record.LastSeen) <= 2*window.d {
+				gone++
+			}
+			if now.Sub(record.FirstSeen) <= window.d {
+				first++
+			}
+		}
+		fmt.Fprintf(tw, "last %s\tseen %d, new %d, gone %d\n", window.name, seen, first, gone)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "(gone: seen in the period before, but not since)")
+
+	printTopCounts(w, "versions", versions, top)
+	printTopCounts(w, "monikers", monikers, top)
+}
+
+func printTopCounts(w io.Writer, title string, counts map[string]int, top int) {
+	if len(counts) == 0 || top <= 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > top {
+		keys = keys[:top]
+	}
+
+	fmt.Fprintf(w, "\n%s of nodes that completed a handshake:\n", title)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, key := range keys {
+		name := key
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(tw, "  %s\t%d\n", name, counts[key])
+	}
+	tw.Flush()
+}